@@ -0,0 +1,96 @@
+package gotfy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Account describes the user (or, without authentication, the visitor) as seen by a Ntfy server.
+// See: https://docs.ntfy.sh/publish/#limitations
+type Account struct {
+	Username string        `json:"username"`       // Username, or "*" for anonymous visitors.
+	Role     string        `json:"role,omitempty"` // "admin" or "user".
+	Limits   AccountLimits `json:"limits"`         // Limits which apply to the account.
+	Stats    AccountStats  `json:"stats"`          // Usage counted against the limits.
+}
+
+// AccountLimits are the limits which apply to an account. Message, e-mail and call limits are daily.
+type AccountLimits struct {
+	Basis                    string `json:"basis,omitempty"`            // "ip" for visitor limits, "tier" for limits from the account's tier.
+	Messages                 int64  `json:"messages"`                   // Messages which may be published per day.
+	MessagesExpiryDuration   int64  `json:"messages_expiry_duration"`   // Seconds for which messages are cached.
+	Emails                   int64  `json:"emails"`                     // E-mails which may be sent per day.
+	Calls                    int64  `json:"calls"`                      // Phone calls which may be made per day.
+	Reservations             int64  `json:"reservations"`               // Topics which may be reserved.
+	AttachmentTotalSize      int64  `json:"attachment_total_size"`      // Total size of stored attachments, in bytes.
+	AttachmentFileSize       int64  `json:"attachment_file_size"`       // Size of a single attachment, in bytes.
+	AttachmentExpiryDuration int64  `json:"attachment_expiry_duration"` // Seconds for which attachments are stored.
+	AttachmentBandwidth      int64  `json:"attachment_bandwidth"`       // Attachment traffic per day, in bytes.
+}
+
+// AccountStats is an account's usage counted against its AccountLimits.
+type AccountStats struct {
+	Messages                     int64 `json:"messages"`
+	MessagesRemaining            int64 `json:"messages_remaining"`
+	Emails                       int64 `json:"emails"`
+	EmailsRemaining              int64 `json:"emails_remaining"`
+	Calls                        int64 `json:"calls"`
+	CallsRemaining               int64 `json:"calls_remaining"`
+	Reservations                 int64 `json:"reservations"`
+	ReservationsRemaining        int64 `json:"reservations_remaining"`
+	AttachmentTotalSize          int64 `json:"attachment_total_size"`
+	AttachmentTotalSizeRemaining int64 `json:"attachment_total_size_remaining"`
+}
+
+// FetchAccount returns the account the given options authenticate as, from the server's
+// /v1/account endpoint. Only Server, Auth, Headers, HttpClient and MaxResponseSize are used.
+func FetchAccount(ctx context.Context, opts PublisherOpts) (*Account, error) {
+	server := serverOrDefault(opts.Server)
+	endpoint := server.JoinPath("v1", "account")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if opts.Headers != nil {
+		req.Header = opts.Headers.Clone()
+	}
+	req.Header.Set("Accept", "application/json")
+	if opts.Auth != nil {
+		req.Header.Set("Authorization", opts.Auth.Header())
+	}
+
+	client := opts.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch account: %w", err)
+	}
+	defer closeBody(resp)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to fetch account: %w", newAPIError(resp))
+	}
+	if resp.Body == nil {
+		return nil, fmt.Errorf("response body is nil")
+	}
+
+	limit := opts.MaxResponseSize
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+	buf, err := readBody(resp, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var account Account
+	if err := json.Unmarshal(buf, &account); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal account from JSON: %w", err)
+	}
+	return &account, nil
+}
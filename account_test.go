@@ -0,0 +1,74 @@
+package gotfy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAccountJSON = `{
+	"username": "phil",
+	"role": "user",
+	"limits": {
+		"basis": "tier",
+		"messages": 4800,
+		"messages_expiry_duration": 43200,
+		"emails": 24,
+		"calls": 0,
+		"reservations": 3,
+		"attachment_total_size": 104857600,
+		"attachment_file_size": 15728640,
+		"attachment_expiry_duration": 21600,
+		"attachment_bandwidth": 1073741824
+	},
+	"stats": {
+		"messages": 10,
+		"messages_remaining": 4790,
+		"emails": 30,
+		"emails_remaining": 0,
+		"calls": 0,
+		"calls_remaining": 0
+	}
+}`
+
+func Test_FetchAccount(t *testing.T) {
+	r := require.New(t)
+
+	u := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Equal(t, "/v1/account", req.URL.Path)
+		assert.Equal(t, "Bearer tk_0123456789", req.Header.Get("Authorization"))
+		_, _ = io.WriteString(w, testAccountJSON)
+	}))
+
+	account, err := FetchAccount(context.Background(), PublisherOpts{Server: u, Auth: AccessToken("tk_0123456789")})
+	r.NoError(err)
+	r.Equal("phil", account.Username)
+	r.Equal(int64(4800), account.Limits.Messages)
+	r.Equal(int64(15728640), account.Limits.AttachmentFileSize)
+	r.Equal(int64(4790), account.Stats.MessagesRemaining)
+
+	limits := account.RateLimits()
+	r.Equal(RateLimit{Burst: 4800, Interval: 18 * time.Second, Used: 10}, limits.Messages)
+	r.Equal(RateLimit{Burst: 24, Interval: time.Hour, Used: 24}, limits.Emails)
+	r.Equal(RateLimit{}, limits.Calls, "a limit the server doesn't report should not block anything")
+	r.False(limits.FailFast)
+	r.NoError(newRateLimiter(limits).wait(context.Background(), &Message{Topic: "mytopic", Call: "+12223334444"}))
+}
+
+func Test_FetchAccount_Error(t *testing.T) {
+	r := require.New(t)
+
+	u := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"code":40101,"http":401,"error":"unauthorized"}`)
+	}))
+
+	_, err := FetchAccount(context.Background(), PublisherOpts{Server: u})
+	r.ErrorIs(err, ErrUnauthorized)
+}
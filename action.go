@@ -1,5 +1,11 @@
 package gotfy
 
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
 // ActionButtonType specifies all the currently supported action buttons
 // that you can use for a notification.
 // See: https://docs.ntfy.sh/publish/#action-buttons
@@ -19,3 +25,97 @@ type ActionButton interface {
 	ButtonType() ActionButtonType
 	MarshalJSON() ([]byte, error)
 }
+
+// ActionButtons is a list of action buttons which can be unmarshalled from JSON.
+// Each button is decoded into the type registered for its "action" field; see RegisterActionButton.
+type ActionButtons []ActionButton
+
+// UnmarshalJSON unmarshals a JSON array of action buttons into their registered types.
+func (a *ActionButtons) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	if raw == nil {
+		*a = nil
+		return nil
+	}
+
+	buttons := make(ActionButtons, 0, len(raw))
+	for _, r := range raw {
+		btn, err := DecodeActionButton(r)
+		if err != nil {
+			return err
+		}
+		buttons = append(buttons, btn)
+	}
+
+	*a = buttons
+	return nil
+}
+
+// UnknownActionError is returned when decoding an action button whose "action" has not been registered.
+type UnknownActionError struct {
+	Action string
+}
+
+func (e *UnknownActionError) Error() string {
+	return fmt.Sprintf("unknown action button type %q", e.Action)
+}
+
+var (
+	actionRegistryMu sync.RWMutex
+	actionRegistry   = map[string]func() ActionButton{
+		"view":      func() ActionButton { return &ViewAction{} },
+		"http":      func() ActionButton { return &HttpAction[string]{} },
+		"broadcast": func() ActionButton { return &BroadcastAction{} },
+	}
+)
+
+// RegisterActionButton makes a custom action button type available for decoding.
+// newButton must return a new, empty button which implements json.Unmarshaler;
+// the JSON of every button whose "action" field equals action is unmarshalled into it.
+// Registering an action again replaces the previous registration, which allows e.g. decoding
+// HTTP actions into an HttpAction with a structured body.
+func RegisterActionButton(action string, newButton func() ActionButton) {
+	if action == "" {
+		panic("gotfy: RegisterActionButton action must not be empty")
+	}
+	if newButton == nil {
+		panic("gotfy: RegisterActionButton newButton must not be nil")
+	}
+
+	actionRegistryMu.Lock()
+	defer actionRegistryMu.Unlock()
+	actionRegistry[action] = newButton
+}
+
+// DecodeActionButton decodes a single action button from its JSON representation,
+// using the type registered for its "action" field.
+// It returns an *UnknownActionError if no type is registered for the action.
+func DecodeActionButton(b []byte) (ActionButton, error) {
+	var head struct {
+		Action string `json:"action"`
+	}
+	if err := json.Unmarshal(b, &head); err != nil {
+		return nil, err
+	}
+
+	actionRegistryMu.RLock()
+	newButton, ok := actionRegistry[head.Action]
+	actionRegistryMu.RUnlock()
+	if !ok {
+		return nil, &UnknownActionError{Action: head.Action}
+	}
+
+	btn := newButton()
+	u, ok := btn.(json.Unmarshaler)
+	if !ok {
+		return nil, fmt.Errorf("action button type %T for %q does not implement json.Unmarshaler", btn, head.Action)
+	}
+	if err := u.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return btn, nil
+}
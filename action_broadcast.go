@@ -0,0 +1,56 @@
+package gotfy
+
+import "encoding/json"
+
+// DefaultBroadcastIntent is the Android intent sent by a BroadcastAction which does not specify one.
+const DefaultBroadcastIntent = "io.heckel.ntfy.USER_ACTION"
+
+// BroadcastAction allows sending an Android broadcast intent when the action button is tapped,
+// e.g. to trigger Tasker or MacroDroid flows.
+// See: https://docs.ntfy.sh/publish/#send-android-broadcast
+type BroadcastAction struct {
+	Label  string            `json:"label"`  // Label of the action button in the notification
+	Intent string            `json:"intent"` // Android intent name; ntfy uses DefaultBroadcastIntent if empty
+	Extras map[string]string `json:"extras"` // Android intent extras
+	Clear  bool              `json:"clear"`  // Clear notification after action button is tapped
+}
+
+func (b *BroadcastAction) ButtonType() ActionButtonType {
+	return ActionButtonTypeBroadcast
+}
+
+func (b *BroadcastAction) MarshalJSON() ([]byte, error) {
+	m := map[string]any{
+		"action": "broadcast",
+		"label":  b.Label,
+	}
+
+	if intent := b.Intent; intent != "" {
+		m["intent"] = intent
+	}
+
+	if extras := b.Extras; len(extras) > 0 {
+		m["extras"] = extras
+	}
+
+	if b.Clear {
+		m["clear"] = true
+	}
+
+	return json.Marshal(m)
+}
+
+func (b *BroadcastAction) UnmarshalJSON(buf []byte) error {
+	var aux struct {
+		Label  string            `json:"label"`
+		Intent string            `json:"intent"`
+		Extras map[string]string `json:"extras"`
+		Clear  bool              `json:"clear"`
+	}
+	if err := json.Unmarshal(buf, &aux); err != nil {
+		return err
+	}
+
+	*b = BroadcastAction(aux)
+	return nil
+}
@@ -0,0 +1,71 @@
+package gotfy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcastActionMarshal(mainTest *testing.T) {
+	testCases := []struct {
+		name        string
+		arg         BroadcastAction
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:     "base case",
+			expected: `{"action":"broadcast","label":""}`,
+		},
+		{
+			name:     "label",
+			arg:      BroadcastAction{Label: "Take picture"},
+			expected: `{"action":"broadcast","label":"Take picture"}`,
+		},
+		{
+			name:     "intent",
+			arg:      BroadcastAction{Intent: "com.example.AN_INTENT"},
+			expected: `{"action":"broadcast","intent":"com.example.AN_INTENT","label":""}`,
+		},
+		{
+			name:     "extras",
+			arg:      BroadcastAction{Extras: map[string]string{"cmd": "pic", "camera": "front"}},
+			expected: `{"action":"broadcast","extras":{"camera":"front","cmd":"pic"},"label":""}`,
+		},
+		{
+			name:     "clear",
+			arg:      BroadcastAction{Clear: true},
+			expected: `{"action":"broadcast","clear":true,"label":""}`,
+		},
+		{
+			name: "everything",
+			arg: BroadcastAction{
+				Label:  "Take picture",
+				Intent: "com.example.AN_INTENT",
+				Extras: map[string]string{"cmd": "pic"},
+				Clear:  true,
+			},
+			expected: `{"action":"broadcast","clear":true,"extras":{"cmd":"pic"},"intent":"com.example.AN_INTENT","label":"Take picture"}`,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		actual, actualErr := tc.arg.MarshalJSON()
+		t.Equal([]byte(tc.expected), actual, tc.name)
+		t.Equal(tc.expectedErr, actualErr, tc.name)
+	}
+}
+
+func TestBroadcastActionDecode(t *testing.T) {
+	a := assert.New(t)
+
+	var actual ActionButtons
+	a.NoError(json.Unmarshal([]byte(`[{"action":"broadcast","label":"Take picture","extras":{"cmd":"pic","camera":"front"},"clear":true}]`), &actual))
+	a.Equal(ActionButtons{&BroadcastAction{
+		Label:  "Take picture",
+		Extras: map[string]string{"cmd": "pic", "camera": "front"},
+		Clear:  true,
+	}}, actual)
+}
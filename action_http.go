@@ -38,20 +38,19 @@ func (h *HttpAction[X]) MarshalJSON() ([]byte, error) {
 		m["headers"] = headers
 	}
 
-	// double marshal body to work for HTTP requests.
-	// the api wants body to be a marshaled JSON string
-	// so double marshal to escape the string
+	// the API wants body to be a string, so bodies of
+	// any other type are sent as their JSON encoding
 	var zeroVal X
 	if body := h.Body; body != zeroVal {
-		buf, err := json.Marshal(h.Body)
-		if err != nil {
-			return nil, err
-		}
-		str, err := json.Marshal(string(buf))
-		if err != nil {
-			return nil, err
+		if str, ok := any(body).(string); ok {
+			m["body"] = str
+		} else {
+			buf, err := json.Marshal(body)
+			if err != nil {
+				return nil, err
+			}
+			m["body"] = string(buf)
 		}
-		m["body"] = str
 	}
 
 	if h.Clear {
@@ -60,3 +59,45 @@ func (h *HttpAction[X]) MarshalJSON() ([]byte, error) {
 
 	return json.Marshal(m)
 }
+
+func (h *HttpAction[X]) UnmarshalJSON(b []byte) error {
+	var aux struct {
+		Label   string            `json:"label"`
+		URL     string            `json:"url"`
+		Method  string            `json:"method"`
+		Headers map[string]string `json:"headers"`
+		Body    string            `json:"body"`
+		Clear   bool              `json:"clear"`
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	var link *url.URL
+	if aux.URL != "" {
+		u, err := url.Parse(aux.URL)
+		if err != nil {
+			return err
+		}
+		link = u
+	}
+
+	var body X
+	if aux.Body != "" {
+		if str, ok := any(&body).(*string); ok {
+			*str = aux.Body
+		} else if err := json.Unmarshal([]byte(aux.Body), &body); err != nil {
+			return err
+		}
+	}
+
+	*h = HttpAction[X]{
+		Label:   aux.Label,
+		URL:     link,
+		Method:  aux.Method,
+		Headers: aux.Headers,
+		Body:    body,
+		Clear:   aux.Clear,
+	}
+	return nil
+}
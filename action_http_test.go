@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHTTPActionMarshal(mainTest *testing.T) {
@@ -48,7 +49,7 @@ func TestHTTPActionMarshal(mainTest *testing.T) {
 			arg: HttpAction[string]{
 				Body: "body",
 			},
-			expected: `{"action":"http","body":"IlwiYm9keVwiIg==","label":""}`,
+			expected: `{"action":"http","body":"body","label":""}`,
 		},
 		{
 			name: "clear",
@@ -67,7 +68,7 @@ func TestHTTPActionMarshal(mainTest *testing.T) {
 				Body:    "body",
 				Clear:   true,
 			},
-			expected: `{"action":"http","body":"IlwiYm9keVwiIg==","clear":true,"headers":{"header":"val"},"label":"label","method":"method","url":"https://github.com/AnthonyHewins/gotfy"}`,
+			expected: `{"action":"http","body":"body","clear":true,"headers":{"header":"val"},"label":"label","method":"method","url":"https://github.com/AnthonyHewins/gotfy"}`,
 		},
 	}
 
@@ -78,3 +79,32 @@ func TestHTTPActionMarshal(mainTest *testing.T) {
 		t.Equal(tc.expectedErr, actualErr, tc.name)
 	}
 }
+
+func TestHTTPActionMarshalJSONBody(t *testing.T) {
+	type body struct {
+		Open bool `json:"open"`
+	}
+
+	a := assert.New(t)
+	actual, err := (&HttpAction[body]{Body: body{Open: true}}).MarshalJSON()
+	a.NoError(err)
+	a.Equal(`{"action":"http","body":"{\"open\":true}","label":""}`, string(actual))
+
+	var decoded HttpAction[body]
+	a.NoError(decoded.UnmarshalJSON(actual))
+	a.Equal(body{Open: true}, decoded.Body)
+}
+
+func TestHTTPActionUnmarshalJSON(t *testing.T) {
+	r := require.New(t)
+
+	var actual HttpAction[string]
+	r.NoError(actual.UnmarshalJSON([]byte(`{"action":"http","label":"Close door","url":"https://api.example.com/door","method":"PUT","headers":{"Authorization":"Bearer zAzsx1sk.."},"body":"{\"action\": \"close\"}","clear":true}`)))
+
+	r.Equal("Close door", actual.Label)
+	r.Equal("https://api.example.com/door", actual.URL.String())
+	r.Equal("PUT", actual.Method)
+	r.Equal(map[string]string{"Authorization": "Bearer zAzsx1sk.."}, actual.Headers)
+	r.Equal(`{"action": "close"}`, actual.Body)
+	r.True(actual.Clear)
+}
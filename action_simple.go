@@ -0,0 +1,406 @@
+package gotfy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// simpleActionKeys lists the keys written in the order they appear in Ntfy's documentation.
+// action, label and url are written without their key; any other keys follow in sorted order.
+var simpleActionKeys = []string{"action", "label", "url", "clear", "method", "body", "intent"}
+
+// simpleActionPositional is the number of leading keys which may be written as bare values.
+const simpleActionPositional = 3
+
+// simpleActionKey matches a section which would be read as key=value, and so can't hold a bare value.
+var simpleActionKey = regexp.MustCompile(`^\s*([-.\w]+)\s*=`)
+
+// FormatSimpleActions encodes actions in Ntfy's simple format, as accepted in the X-Actions header, e.g.
+//
+//	view, Open portal, https://home.nest.com/, clear=true; http, Turn down, https://api.nest.com/, body='{"temp": 65}'
+//
+// Each action is encoded from its JSON form, so that any registered action type is supported.
+// Nested objects, such as HTTP headers and broadcast extras, are written as headers.<name>=<value>.
+// Values are quoted with double or single quotes where needed, escaping the quote character with a
+// backslash. A value which needs quoting can't end in a backslash, as it would escape the closing quote.
+// See: https://docs.ntfy.sh/publish/#using-a-header
+func FormatSimpleActions(actions []ActionButton) (string, error) {
+	parts := make([]string, 0, len(actions))
+	for _, a := range actions {
+		s, err := formatSimpleAction(a)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, "; "), nil
+}
+
+func formatSimpleAction(a ActionButton) (string, error) {
+	buf, err := json.Marshal(a)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal action to JSON: %w", err)
+	}
+
+	var fields map[string]any
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return "", fmt.Errorf("failed to unmarshal action from JSON: %w", err)
+	}
+
+	flat := make(map[string]string, len(fields))
+	for key, value := range fields {
+		if obj, ok := value.(map[string]any); ok {
+			for name, v := range obj {
+				s, err := simpleActionValue(key+"."+name, v)
+				if err != nil {
+					return "", err
+				}
+				flat[key+"."+name] = s
+			}
+			continue
+		}
+
+		s, err := simpleActionValue(key, value)
+		if err != nil {
+			return "", err
+		}
+		flat[key] = s
+	}
+
+	var sections []string
+	positional := true
+	for i, key := range simpleActionKeys {
+		value, ok := flat[key]
+		if !ok {
+			positional = false
+			continue
+		}
+		delete(flat, key)
+
+		bare := positional && i < simpleActionPositional
+		quoted, err := quoteSimpleActionValue(key, value, bare)
+		if err != nil {
+			return "", err
+		}
+		if bare {
+			sections = append(sections, quoted)
+		} else {
+			sections = append(sections, key+"="+quoted)
+		}
+	}
+
+	rest := make([]string, 0, len(flat))
+	for key := range flat {
+		rest = append(rest, key)
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		quoted, err := quoteSimpleActionValue(key, flat[key], false)
+		if err != nil {
+			return "", err
+		}
+		sections = append(sections, key+"="+quoted)
+	}
+
+	return strings.Join(sections, ", "), nil
+}
+
+func simpleActionValue(key string, v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("action field %q cannot be written in the simple format", key)
+	}
+}
+
+// quoteSimpleActionValue quotes the value of key if it would otherwise be misread, preferring
+// whichever quote character it doesn't contain. Bare values must also not look like key=value.
+// Values containing only one kind of quote are quoted with the other, for readability.
+func quoteSimpleActionValue(key, value string, bare bool) (string, error) {
+	needsQuotes := value == "" ||
+		strings.ContainsAny(value, ",;") ||
+		strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") ||
+		strings.TrimSpace(value) != value ||
+		(bare && simpleActionKey.MatchString(value))
+	hasBothQuotes := strings.Contains(value, `"`) && strings.Contains(value, "'")
+	if !needsQuotes && (!strings.ContainsAny(value, `"'`) || hasBothQuotes) {
+		return value, nil
+	}
+	if strings.HasSuffix(value, `\`) {
+		return "", fmt.Errorf("action field %q cannot be written in the simple format: it needs quoting, but ends in a backslash", key)
+	}
+
+	quote := `"`
+	if strings.Contains(value, `"`) && !strings.Contains(value, "'") {
+		quote = "'"
+	}
+	return quote + strings.ReplaceAll(value, quote, `\`+quote) + quote, nil
+}
+
+// ParseSimpleActions decodes actions written in Ntfy's simple format, as produced by FormatSimpleActions.
+// Actions are separated by semicolons and their fields by commas. The action, label and url may be
+// given by position or as key=value; all other fields are key=value, with headers.<name> and
+// extras.<name> setting HTTP headers and broadcast extras. Keys are case-insensitive. Values containing
+// commas or semicolons must be quoted with double or single quotes; within them, a backslash escapes
+// the quote character.
+//
+// Each action is decoded by the type registered for it; see RegisterActionButton. The built-in actions
+// only accept their own keys, while custom types are passed any key as a string, or as an object
+// for <key>.<name> keys.
+// See: https://docs.ntfy.sh/publish/#using-a-header
+func ParseSimpleActions(s string) ([]ActionButton, error) {
+	p := simpleActionParser{input: []rune(s)}
+
+	var actions []ActionButton
+	for n := 1; ; n++ {
+		p.skipSpace()
+		if p.eof() {
+			return actions, nil
+		}
+
+		fields, err := p.parseAction()
+		if err == nil {
+			err = checkSimpleActionFields(fields)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid action %d: %w", n, err)
+		}
+
+		buf, err := json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("invalid action %d: %w", n, err)
+		}
+		btn, err := DecodeActionButton(buf)
+		if err != nil {
+			return nil, fmt.Errorf("invalid action %d: %w", n, err)
+		}
+		actions = append(actions, btn)
+	}
+}
+
+// UnmarshalText decodes action buttons from Ntfy's simple format, so that they can be
+// written that way in configuration files. See ParseSimpleActions.
+func (a *ActionButtons) UnmarshalText(b []byte) error {
+	actions, err := ParseSimpleActions(string(b))
+	if err != nil {
+		return err
+	}
+	*a = actions
+	return nil
+}
+
+type simpleActionParser struct {
+	input []rune
+	pos   int
+}
+
+// parseAction reads the fields of a single action, up to and including the semicolon ending it.
+func (p *simpleActionParser) parseAction() (map[string]any, error) {
+	fields := make(map[string]any)
+
+	// as in Ntfy, a value without a key is taken by its position among all of the action's values
+	for i := 0; ; i++ {
+		key, value, err := p.parseSection()
+		if err != nil {
+			return nil, err
+		}
+
+		if key == "" {
+			if i >= simpleActionPositional {
+				return nil, fmt.Errorf("unexpected value %q; only the action, label and url may be given without a key", value)
+			}
+			key = simpleActionKeys[i]
+		}
+		if err := setSimpleActionField(fields, key, value); err != nil {
+			return nil, err
+		}
+
+		switch p.next() {
+		case ',':
+			continue
+		case ';', 0:
+			if _, ok := fields["action"]; !ok {
+				return nil, errors.New("action type missing")
+			}
+			return fields, nil
+		}
+	}
+}
+
+// parseSection reads a single value, with its key if it has one, leaving the separator after it unread.
+func (p *simpleActionParser) parseSection() (key, value string, err error) {
+	p.skipSpace()
+	if m := simpleActionKey.FindStringSubmatch(string(p.input[p.pos:])); m != nil {
+		// keys are case-insensitive, but the names of headers and extras are kept as they are
+		key = m[1]
+		if i := strings.Index(key, "."); i >= 0 {
+			key = strings.ToLower(key[:i]) + key[i:]
+		} else {
+			key = strings.ToLower(key)
+		}
+		p.pos += len([]rune(m[0]))
+		p.skipSpace()
+	}
+
+	if r := p.peek(); r == '"' || r == '\'' {
+		start := p.pos
+		value, err = p.parseQuoted(r)
+		if err != nil {
+			return "", "", err
+		}
+		p.skipSpace()
+		if r := p.peek(); r != ',' && r != ';' && r != 0 {
+			return "", "", fmt.Errorf("unexpected %q after the value quoted at position %d", r, start+1)
+		}
+		return key, value, nil
+	}
+
+	start := p.pos
+	for r := p.peek(); r != ',' && r != ';' && r != 0; r = p.peek() {
+		p.pos++
+	}
+	return key, strings.TrimSpace(string(p.input[start:p.pos])), nil
+}
+
+// parseQuoted reads a value enclosed in the given quote character, in which a backslash escapes
+// the quote character. Any other backslash is kept as it is, as Ntfy does.
+func (p *simpleActionParser) parseQuoted(quote rune) (string, error) {
+	start := p.pos
+	p.pos++
+
+	var prev rune
+	for {
+		r := p.next()
+		switch {
+		case r == 0:
+			return "", fmt.Errorf("unterminated quote at position %d", start+1)
+		case r == quote && prev != '\\':
+			value := string(p.input[start+1 : p.pos-1])
+			return strings.ReplaceAll(value, `\`+string(quote), string(quote)), nil
+		}
+		prev = r
+	}
+}
+
+func (p *simpleActionParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+// peek returns the next rune without consuming it, or 0 at the end of the input.
+func (p *simpleActionParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// next consumes and returns the next rune, or 0 at the end of the input.
+func (p *simpleActionParser) next() rune {
+	r := p.peek()
+	if r != 0 {
+		p.pos++
+	}
+	return r
+}
+
+func (p *simpleActionParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// setSimpleActionField sets the field key of an action's JSON form to value.
+// A key of the form <object>.<name> sets name within the object, as for headers and extras.
+func setSimpleActionField(fields map[string]any, key, value string) error {
+	if i := strings.Index(key, "."); i >= 0 {
+		obj, name := key[:i], key[i+1:]
+		if obj == "" || name == "" {
+			return fmt.Errorf("key %q is missing a name", key)
+		}
+		m, ok := fields[obj].(map[string]string)
+		if !ok {
+			if _, set := fields[obj]; set {
+				return fmt.Errorf("key %q conflicts with %q", key, obj)
+			}
+			m = make(map[string]string)
+			fields[obj] = m
+		}
+		if _, ok := m[name]; ok {
+			return fmt.Errorf("duplicate key %q", key)
+		}
+		m[name] = value
+		return nil
+	}
+
+	if _, ok := fields[key]; ok {
+		return fmt.Errorf("duplicate key %q", key)
+	}
+
+	if key == "clear" {
+		switch strings.ToLower(value) {
+		case "true", "yes", "1":
+			fields[key] = true
+		case "false", "no", "0":
+			fields[key] = false
+		default:
+			return fmt.Errorf("invalid value %q for clear", value)
+		}
+		return nil
+	}
+
+	fields[key] = value
+	return nil
+}
+
+// simpleActionFields lists the keys each built-in action accepts, and whether each is an object,
+// given as <key>.<name>=<value>. Actions of any other type accept any key.
+var simpleActionFields = map[string]map[string]bool{
+	"view":      {"action": false, "label": false, "url": false, "clear": false},
+	"http":      {"action": false, "label": false, "url": false, "clear": false, "method": false, "headers": true, "body": false},
+	"broadcast": {"action": false, "label": false, "clear": false, "intent": false, "extras": true},
+}
+
+// checkSimpleActionFields rejects keys which the action doesn't have, if it is a built-in one.
+func checkSimpleActionFields(fields map[string]any) error {
+	action, _ := fields["action"].(string)
+	allowed, ok := simpleActionFields[action]
+	if !ok {
+		return nil
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		object, ok := allowed[key]
+		if !ok {
+			return fmt.Errorf("unknown key %q for %s actions", key, action)
+		}
+		if _, isObject := fields[key].(map[string]string); isObject != object {
+			if object {
+				return fmt.Errorf("key %q must be given as %s.<name>", key, key)
+			}
+			return fmt.Errorf("key %q does not take a name", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package gotfy
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FormatSimpleActions(t *testing.T) {
+	link := func(s string) *url.URL {
+		u, err := url.Parse(s)
+		require.NoError(t, err)
+		return u
+	}
+
+	for _, tc := range []struct {
+		name    string
+		actions []ActionButton
+		want    string
+	}{
+		{
+			name:    "view",
+			actions: []ActionButton{&ViewAction{Label: "Open portal", Link: link("https://home.nest.com/"), Clear: true}},
+			want:    "view, Open portal, https://home.nest.com/, clear=true",
+		},
+		{
+			name: "http with headers and body",
+			actions: []ActionButton{&HttpAction[string]{
+				Label:   "Close door",
+				URL:     link("https://api.mygarage.lan/"),
+				Method:  "PUT",
+				Headers: map[string]string{"Authorization": "Bearer zAzsx1sk..", "X-Mode": "close"},
+				Body:    `{"action": "close"}`,
+			}},
+			want: `http, Close door, https://api.mygarage.lan/, method=PUT, body='{"action": "close"}', headers.Authorization=Bearer zAzsx1sk.., headers.X-Mode=close`,
+		},
+		{
+			name: "broadcast with extras",
+			actions: []ActionButton{&BroadcastAction{
+				Label:  "Take picture",
+				Extras: map[string]string{"cmd": "pic", "camera": "front"},
+			}},
+			want: "broadcast, Take picture, extras.camera=front, extras.cmd=pic",
+		},
+		{
+			name: "several actions",
+			actions: []ActionButton{
+				&ViewAction{Label: "Open", Link: link("https://example.com")},
+				&BroadcastAction{Label: "Ack", Intent: "com.example.ACK", Clear: true},
+			},
+			want: "view, Open, https://example.com; broadcast, Ack, clear=true, intent=com.example.ACK",
+		},
+		{
+			name: "quoting",
+			actions: []ActionButton{&HttpAction[string]{
+				Label:  "Yes, please; now",
+				URL:    link("https://example.com/?a=b,c"),
+				Method: " POST",
+				Body:   `{"done": true, "ok": 1}`,
+			}},
+			want: `http, "Yes, please; now", "https://example.com/?a=b,c", method=" POST", body='{"done": true, "ok": 1}'`,
+		},
+		{
+			name:    "quotes within values",
+			actions: []ActionButton{&HttpAction[string]{Label: `it's "done"`, URL: link("https://example.com"), Body: `"quoted"`}},
+			want:    `http, it's "done", https://example.com, body='"quoted"'`,
+		},
+		{
+			name:    "bare values which look like keys",
+			actions: []ActionButton{&ViewAction{Label: "clear=true", Link: link("https://example.com")}},
+			want:    `view, "clear=true", https://example.com`,
+		},
+		{
+			name:    "label missing",
+			actions: []ActionButton{&ViewAction{Link: link("https://example.com")}},
+			want:    `view, "", https://example.com`,
+		},
+		{
+			name:    "escaped quotes",
+			actions: []ActionButton{&HttpAction[string]{Label: `Look ma, "quotes"; and semicolons`, URL: link("https://example.com"), Body: `{"it's": "done", "ok": true}`}},
+			want:    `http, 'Look ma, "quotes"; and semicolons', https://example.com, body="{\"it's\": \"done\", \"ok\": true}"`,
+		},
+		{
+			name:    "other backslashes",
+			actions: []ActionButton{&ViewAction{Label: `C:\dir\, or \'quoted\'`, Link: link("https://example.com")}},
+			want:    `view, "C:\dir\, or \'quoted\'", https://example.com`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FormatSimpleActions(tc.actions)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func Test_FormatSimpleActions_TrailingBackslash(t *testing.T) {
+	u, err := url.Parse("https://example.com")
+	require.NoError(t, err)
+
+	// the backslash would escape the closing quote
+	_, err = FormatSimpleActions([]ActionButton{&HttpAction[string]{Label: "x", URL: u, Body: `a; b\`}})
+	require.EqualError(t, err, `action field "body" cannot be written in the simple format: it needs quoting, but ends in a backslash`)
+}
+
+func Test_ParseSimpleActions(t *testing.T) {
+	link := func(s string) *url.URL {
+		u, err := url.Parse(s)
+		require.NoError(t, err)
+		return u
+	}
+
+	// Examples from https://docs.ntfy.sh/publish/#action-buttons
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  []ActionButton
+	}{
+		{
+			name:  "view and http",
+			input: `view, Open portal, https://home.nest.com/, clear=true; http, Turn down, https://api.nest.com/, body='{"temp": 65}'`,
+			want: []ActionButton{
+				&ViewAction{Label: "Open portal", Link: link("https://home.nest.com/"), Clear: true},
+				&HttpAction[string]{Label: "Turn down", URL: link("https://api.nest.com/"), Body: `{"temp": 65}`},
+			},
+		},
+		{
+			name:  "view",
+			input: "view, Open Twitter, https://twitter.com/binwiederhier/status/1467633927951163392",
+			want:  []ActionButton{&ViewAction{Label: "Open Twitter", Link: link("https://twitter.com/binwiederhier/status/1467633927951163392")}},
+		},
+		{
+			name:  "keys instead of positions",
+			input: "action=view, label=Open portal, url=https://home.nest.com/, clear=true",
+			want:  []ActionButton{&ViewAction{Label: "Open portal", Link: link("https://home.nest.com/"), Clear: true}},
+		},
+		{
+			name:  "broadcast with extras",
+			input: "broadcast, Take picture, extras.cmd=pic, extras.camera=front",
+			want: []ActionButton{&BroadcastAction{
+				Label:  "Take picture",
+				Extras: map[string]string{"cmd": "pic", "camera": "front"},
+			}},
+		},
+		{
+			name:  "broadcast with intent",
+			input: "action=broadcast, label=Take picture, intent=io.heckel.ntfy.USER_ACTION, extras.cmd=pic",
+			want: []ActionButton{&BroadcastAction{
+				Label:  "Take picture",
+				Intent: "io.heckel.ntfy.USER_ACTION",
+				Extras: map[string]string{"cmd": "pic"},
+			}},
+		},
+		{
+			name:  "http with headers",
+			input: `http, Close door, https://api.mygarage.lan/, method=PUT, headers.Authorization=Bearer zAzsx1sk.., body={"action": "close"}`,
+			want: []ActionButton{&HttpAction[string]{
+				Label:   "Close door",
+				URL:     link("https://api.mygarage.lan/"),
+				Method:  "PUT",
+				Headers: map[string]string{"Authorization": "Bearer zAzsx1sk.."},
+				Body:    `{"action": "close"}`,
+			}},
+		},
+		{
+			name:  "http clearing the notification",
+			input: "http, Open door, https://api.nest.com/open/yAxkasd, clear=true",
+			want:  []ActionButton{&HttpAction[string]{Label: "Open door", URL: link("https://api.nest.com/open/yAxkasd"), Clear: true}},
+		},
+		{
+			name:  "quoted values",
+			input: `http, "Turn down, please", https://api.nest.com/, body='{"temp": 65}'; view, 'Say "hi"', "https://example.com/?a=b,c"`,
+			want: []ActionButton{
+				&HttpAction[string]{Label: "Turn down, please", URL: link("https://api.nest.com/"), Body: `{"temp": 65}`},
+				&ViewAction{Label: `Say "hi"`, Link: link("https://example.com/?a=b,c")},
+			},
+		},
+		{
+			name:  "escaped quotes",
+			input: `http, "Look ma, \"quotes\"; and semicolons", url=http://example.com`,
+			want:  []ActionButton{&HttpAction[string]{Label: `Look ma, "quotes"; and semicolons`, URL: link("http://example.com")}},
+		},
+		{
+			name:  "escaped quotes in JSON",
+			input: `http, Post, http://example.com, body="{\"temp\": 65}"; http, Post, http://example.com, body='{"name": "it\'s me"}'`,
+			want: []ActionButton{
+				&HttpAction[string]{Label: "Post", URL: link("http://example.com"), Body: `{"temp": 65}`},
+				&HttpAction[string]{Label: "Post", URL: link("http://example.com"), Body: `{"name": "it's me"}`},
+			},
+		},
+		{
+			name:  "other backslashes are kept",
+			input: `view, "C:\dir\file, \'quoted\'", http://example.com`,
+			want:  []ActionButton{&ViewAction{Label: `C:\dir\file, \'quoted\'`, Link: link("http://example.com")}},
+		},
+		{
+			name:  "keys in upper case",
+			input: "http, Open door, https://example.com, Method=PUT, Clear=true, Headers.X-Token=abc",
+			want: []ActionButton{&HttpAction[string]{
+				Label: "Open door", URL: link("https://example.com"), Method: "PUT", Clear: true,
+				Headers: map[string]string{"X-Token": "abc"},
+			}},
+		},
+		{
+			name:  "whitespace and trailing separator",
+			input: "  view ,Open,https://example.com ;  ",
+			want:  []ActionButton{&ViewAction{Label: "Open", Link: link("https://example.com")}},
+		},
+		{
+			name:  "empty",
+			input: "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			got, err := ParseSimpleActions(tc.input)
+			r.NoError(err)
+			r.Equal(tc.want, got)
+
+			// the serializer produces input the parser reads back the same
+			formatted, err := FormatSimpleActions(got)
+			r.NoError(err)
+			again, err := ParseSimpleActions(formatted)
+			r.NoError(err)
+			r.Equal(got, again)
+		})
+	}
+}
+
+func Test_ParseSimpleActions_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		want  string
+	}{
+		{`view, "Open, https://example.com`, "invalid action 1: unterminated quote at position 7"},
+		{`view, "Open" now, https://example.com`, `invalid action 1: unexpected 'n' after the value quoted at position 7`},
+		{`view, Open, https://example.com, extra`, `invalid action 1: unexpected value "extra"; only the action, label and url may be given without a key`},
+		{`view, Open, https://example.com; dance, Go`, `invalid action 2: unknown action button type "dance"`},
+		{`http, Open, https://example.com, headers=x, headers.a=b`, `invalid action 1: key "headers.a" conflicts with "headers"`},
+		{`http, Open, https://example.com, headers.a=b, headers.a=c`, `invalid action 1: duplicate key "headers.a"`},
+		{`view, Open, https://example.com, clear=maybe`, `invalid action 1: invalid value "maybe" for clear`},
+		{`label=Open, url=https://example.com`, "invalid action 1: action type missing"},
+		{`http, Open, https://example.com, headers.=x`, `invalid action 1: key "headers." is missing a name`},
+		{`view, Open, https://example.com, label=Again`, `invalid action 1: duplicate key "label"`},
+		{`view, Open, https://example.com, colour=red, cleer=true`, `invalid action 1: unknown key "cleer" for view actions`},
+		{`view, Open, url=https://example.com, extra`, `invalid action 1: unexpected value "extra"; only the action, label and url may be given without a key`},
+		{`broadcast, Take picture, https://example.com`, `invalid action 1: unknown key "url" for broadcast actions`},
+		{`broadcast, Take picture, extras=x`, `invalid action 1: key "extras" must be given as extras.<name>`},
+		{`view, Open, https://example.com, clear.x=y`, `invalid action 1: key "clear" does not take a name`},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := ParseSimpleActions(tc.input)
+			require.EqualError(t, err, tc.want)
+		})
+	}
+}
+
+func Test_ActionButtons_UnmarshalText(t *testing.T) {
+	r := require.New(t)
+
+	var config struct {
+		Actions ActionButtons `json:"actions"`
+	}
+	r.NoError(config.Actions.UnmarshalText([]byte("view, Open, https://example.com")))
+	r.Len(config.Actions, 1)
+	r.Equal("Open", config.Actions[0].(*ViewAction).Label)
+}
+
+// snoozeAction is a custom action type, with fields the built-in actions don't have.
+type snoozeAction struct {
+	Label    string            `json:"label"`
+	Duration string            `json:"duration"`
+	Rooms    map[string]string `json:"rooms,omitempty"`
+}
+
+func (a *snoozeAction) ButtonType() ActionButtonType {
+	return ActionButtonTypeUnspecified
+}
+
+func (a *snoozeAction) MarshalJSON() ([]byte, error) {
+	type plain snoozeAction
+	return json.Marshal(struct {
+		Action string `json:"action"`
+		*plain
+	}{"snooze", (*plain)(a)})
+}
+
+func (a *snoozeAction) UnmarshalJSON(b []byte) error {
+	type plain snoozeAction
+	return json.Unmarshal(b, (*plain)(a))
+}
+
+func Test_SimpleActions_CustomType(t *testing.T) {
+	r := require.New(t)
+
+	RegisterActionButton("snooze", func() ActionButton { return &snoozeAction{} })
+	defer func() {
+		actionRegistryMu.Lock()
+		delete(actionRegistry, "snooze")
+		actionRegistryMu.Unlock()
+	}()
+
+	actions := []ActionButton{&snoozeAction{Label: "Nap", Duration: "15m", Rooms: map[string]string{"kitchen": "off"}}}
+	formatted, err := FormatSimpleActions(actions)
+	r.NoError(err)
+	r.Equal("snooze, Nap, duration=15m, rooms.kitchen=off", formatted)
+
+	parsed, err := ParseSimpleActions(formatted)
+	r.NoError(err)
+	r.Equal(actions, parsed)
+}
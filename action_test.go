@@ -0,0 +1,85 @@
+package gotfy
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testAction struct {
+	Label string `json:"label"`
+}
+
+func (t *testAction) ButtonType() ActionButtonType {
+	return ActionButtonTypeUnspecified
+}
+
+func (t *testAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"action": "test", "label": t.Label})
+}
+
+func (t *testAction) UnmarshalJSON(b []byte) error {
+	type plain testAction
+	return json.Unmarshal(b, (*plain)(t))
+}
+
+func TestActionButtons_UnmarshalJSON(t *testing.T) {
+	r := require.New(t)
+
+	var actual ActionButtons
+	r.NoError(json.Unmarshal([]byte(`[
+		{"action":"view","label":"Open","url":"https://example.com"},
+		{"action":"http","label":"Close","url":"https://api.example.com","body":"close"}
+	]`), &actual))
+
+	r.Len(actual, 2)
+	r.Equal(ActionButtonTypeView, actual[0].ButtonType())
+	r.Equal("Open", actual[0].(*ViewAction).Label)
+	r.Equal(ActionButtonTypeHTTP, actual[1].ButtonType())
+	r.Equal("close", actual[1].(*HttpAction[string]).Body)
+
+	r.NoError(json.Unmarshal([]byte(`null`), &actual))
+	r.Nil(actual)
+}
+
+func TestActionButtons_UnknownAction(t *testing.T) {
+	r := require.New(t)
+
+	var actual ActionButtons
+	err := json.Unmarshal([]byte(`[{"action":"teleport","label":"Beam me up"}]`), &actual)
+
+	var unknown *UnknownActionError
+	r.True(errors.As(err, &unknown), "unexpected error: %v", err)
+	r.Equal("teleport", unknown.Action)
+}
+
+func TestRegisterActionButton(t *testing.T) {
+	r := require.New(t)
+
+	RegisterActionButton("test", func() ActionButton { return &testAction{} })
+	defer func() {
+		actionRegistryMu.Lock()
+		delete(actionRegistry, "test")
+		actionRegistryMu.Unlock()
+	}()
+
+	btn, err := DecodeActionButton([]byte(`{"action":"test","label":"custom"}`))
+	r.NoError(err)
+	r.Equal(&testAction{Label: "custom"}, btn)
+}
+
+func TestRegisterActionButton_Replace(t *testing.T) {
+	r := require.New(t)
+
+	type body struct {
+		Open bool `json:"open"`
+	}
+	RegisterActionButton("http", func() ActionButton { return &HttpAction[body]{} })
+	defer RegisterActionButton("http", func() ActionButton { return &HttpAction[string]{} })
+
+	btn, err := DecodeActionButton([]byte(`{"action":"http","label":"Open","body":"{\"open\":true}"}`))
+	r.NoError(err)
+	r.Equal(body{Open: true}, btn.(*HttpAction[body]).Body)
+}
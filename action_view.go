@@ -39,3 +39,30 @@ func (v *ViewAction) MarshalJSON() ([]byte, error) {
 
 	return append(buf, '}'), nil
 }
+
+func (v *ViewAction) UnmarshalJSON(b []byte) error {
+	var aux struct {
+		Label string `json:"label"`
+		URL   string `json:"url"`
+		Clear bool   `json:"clear"`
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	var link *url.URL
+	if aux.URL != "" {
+		u, err := url.Parse(aux.URL)
+		if err != nil {
+			return err
+		}
+		link = u
+	}
+
+	*v = ViewAction{
+		Label: aux.Label,
+		Link:  link,
+		Clear: aux.Clear,
+	}
+	return nil
+}
@@ -58,3 +58,13 @@ func TestViewMarshalJSON(mainTest *testing.T) {
 		t.Equal(tc.expectedErr, actualErr, tc.name)
 	}
 }
+
+func TestViewUnmarshalJSON(t *testing.T) {
+	a := assert.New(t)
+
+	var actual ViewAction
+	a.NoError(actual.UnmarshalJSON([]byte(`{"action":"view","label":"Open portal","url":"https://home.nest.com/","clear":true}`)))
+	a.Equal("Open portal", actual.Label)
+	a.Equal("https://home.nest.com/", actual.Link.String())
+	a.True(actual.Clear)
+}
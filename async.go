@@ -0,0 +1,276 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// AsyncPublisher queues messages in memory and sends them in the background through another Publisher.
+type AsyncPublisher interface {
+	// Enqueue adds m to the queue and returns without waiting for it to be sent. Once the message has
+	// been sent or has failed, done is called with the result from a worker goroutine; if the message
+	// is dropped, done is called with ErrMessageDropped from within Enqueue. done may be nil.
+	//
+	// ctx only bounds how long Enqueue waits for room in the queue under OverflowBlock; the message
+	// itself is sent independently of the caller's context.
+	Enqueue(ctx context.Context, m Message, done SendCallback) error
+
+	// Flush waits until every message enqueued so far has been sent, failed or been dropped.
+	Flush(ctx context.Context) error
+
+	// Close stops accepting new messages and waits for the outstanding ones to be sent.
+	// If ctx ends first, sends still in progress are cancelled, their callbacks receive the
+	// resulting errors, and Close returns once the workers have stopped.
+	Close(ctx context.Context) error
+}
+
+// SendCallback receives the outcome of a message sent by an AsyncPublisher.
+type SendCallback func(resp *SendResponse, err error)
+
+// OverflowPolicy decides what AsyncPublisher.Enqueue does when the queue is full.
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // Wait for room in the queue.
+	OverflowDropOldest                       // Drop the oldest queued message to make room.
+	OverflowDropNewest                       // Drop the message being enqueued.
+	OverflowError                            // Return ErrQueueFull.
+)
+
+// Defaults used when the corresponding AsyncPublisherOpts field is zero.
+const (
+	DefaultAsyncQueueSize = 100
+	DefaultAsyncWorkers   = 1
+)
+
+var (
+	// ErrQueueFull is returned by Enqueue under OverflowError when the queue is full.
+	ErrQueueFull = errors.New("queue is full")
+
+	// ErrMessageDropped is passed to the callback of a message dropped under
+	// OverflowDropOldest or OverflowDropNewest.
+	ErrMessageDropped = errors.New("message dropped: queue is full")
+
+	// ErrPublisherClosed is returned by Enqueue once Close has been called.
+	ErrPublisherClosed = errors.New("publisher is closed")
+)
+
+// AsyncPublisherOpts contains the configuration options for a new AsyncPublisher.
+type AsyncPublisherOpts struct {
+	// Publisher sends the queued messages. It must be safe for concurrent use if Workers is above one.
+	Publisher Publisher
+
+	// QueueSize is the number of messages that may wait to be sent. If zero, DefaultAsyncQueueSize is used.
+	QueueSize int
+
+	// Workers is the number of messages sent concurrently. If zero, DefaultAsyncWorkers is used.
+	// With more than one worker, messages may be delivered out of order.
+	Workers int
+
+	// Overflow decides what happens when the queue is full. Defaults to OverflowBlock.
+	Overflow OverflowPolicy
+
+	// SendTimeout, if set, bounds each call to Publisher.Send.
+	SendTimeout time.Duration
+}
+
+type asyncMessage struct {
+	m    Message
+	done SendCallback
+}
+
+type asyncPublisher struct {
+	publisher   Publisher
+	queueSize   int
+	overflow    OverflowPolicy
+	sendTimeout time.Duration
+
+	// ctx is the parent of every send, and is cancelled when Close gives up waiting.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	queue   []asyncMessage
+	pending int           // queued or being sent
+	closed  bool          // no more messages are accepted
+	changed chan struct{} // closed and replaced whenever any of the above changes
+
+	workers sync.WaitGroup
+}
+
+// NewAsyncPublisher creates an AsyncPublisher and starts its workers.
+func NewAsyncPublisher(opts AsyncPublisherOpts) (AsyncPublisher, error) {
+	if opts.Publisher == nil {
+		return nil, errors.New("publisher must not be nil")
+	}
+
+	retv := &asyncPublisher{
+		publisher:   opts.Publisher,
+		queueSize:   opts.QueueSize,
+		overflow:    opts.Overflow,
+		sendTimeout: opts.SendTimeout,
+		changed:     make(chan struct{}),
+	}
+	if retv.queueSize <= 0 {
+		retv.queueSize = DefaultAsyncQueueSize
+	}
+	retv.ctx, retv.cancel = context.WithCancel(context.Background())
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultAsyncWorkers
+	}
+	retv.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go retv.work()
+	}
+
+	return retv, nil
+}
+
+func (p *asyncPublisher) Enqueue(ctx context.Context, m Message, done SendCallback) error {
+	p.mu.Lock()
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return ErrPublisherClosed
+		}
+		if len(p.queue) < p.queueSize {
+			break
+		}
+
+		switch p.overflow {
+		case OverflowDropOldest:
+			dropped := p.queue[0]
+			p.queue = p.queue[1:]
+			p.pending--
+			p.notify()
+			p.mu.Unlock()
+			dropped.complete(nil, ErrMessageDropped)
+			p.mu.Lock()
+		case OverflowDropNewest:
+			p.mu.Unlock()
+			asyncMessage{m: m, done: done}.complete(nil, ErrMessageDropped)
+			return nil
+		case OverflowError:
+			p.mu.Unlock()
+			return ErrQueueFull
+		default:
+			changed := p.changed
+			p.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-changed:
+			}
+			p.mu.Lock()
+		}
+	}
+
+	p.queue = append(p.queue, asyncMessage{m: m, done: done})
+	p.pending++
+	p.notify()
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *asyncPublisher) Flush(ctx context.Context) error {
+	return p.wait(ctx, func() bool { return p.pending == 0 })
+}
+
+func (p *asyncPublisher) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		p.notify()
+	}
+	p.mu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-stopped
+		return ctx.Err()
+	}
+}
+
+// wait blocks until cond, which is evaluated with p.mu held, is true or ctx ends.
+func (p *asyncPublisher) wait(ctx context.Context, cond func() bool) error {
+	for {
+		p.mu.Lock()
+		ok, changed := cond(), p.changed
+		p.mu.Unlock()
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// notify wakes everyone waiting for a change. p.mu must be held.
+func (p *asyncPublisher) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+func (p *asyncPublisher) work() {
+	defer p.workers.Done()
+
+	for {
+		p.mu.Lock()
+		for len(p.queue) == 0 && !p.closed {
+			changed := p.changed
+			p.mu.Unlock()
+			<-changed
+			p.mu.Lock()
+		}
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		msg := p.queue[0]
+		p.queue[0] = asyncMessage{} // release the message for garbage collection
+		p.queue = p.queue[1:]
+		p.notify()
+		p.mu.Unlock()
+
+		msg.complete(p.send(msg.m))
+
+		p.mu.Lock()
+		p.pending--
+		p.notify()
+		p.mu.Unlock()
+	}
+}
+
+func (p *asyncPublisher) send(m Message) (*SendResponse, error) {
+	ctx := p.ctx
+	if p.sendTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.sendTimeout)
+		defer cancel()
+	}
+	return p.publisher.Send(ctx, m)
+}
+
+func (a asyncMessage) complete(resp *SendResponse, err error) {
+	if a.done != nil {
+		a.done(resp, err)
+	}
+}
@@ -0,0 +1,245 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// publisherFunc adapts a function to the Publisher interface.
+type publisherFunc func(ctx context.Context, m Message) (*SendResponse, error)
+
+func (f publisherFunc) Send(ctx context.Context, m Message) (*SendResponse, error) {
+	return f(ctx, m)
+}
+
+// gatedPublisher holds every Send until the gate is opened.
+func gatedPublisher(gate <-chan struct{}, started chan<- string) Publisher {
+	return publisherFunc(func(ctx context.Context, m Message) (*SendResponse, error) {
+		if started != nil {
+			started <- m.Message
+		}
+		select {
+		case <-gate:
+			return &SendResponse{MessageEvent: MessageEvent{ID: m.Message}}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+}
+
+// asyncResults collects the outcome of each message by its body.
+type asyncResults struct {
+	mu      sync.Mutex
+	results map[string]error
+}
+
+func (a *asyncResults) callback(msg string) SendCallback {
+	return func(resp *SendResponse, err error) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.results == nil {
+			a.results = make(map[string]error)
+		}
+		if err == nil && resp.ID != msg {
+			err = errors.New("unexpected response " + resp.ID)
+		}
+		a.results[msg] = err
+	}
+}
+
+func (a *asyncResults) get() map[string]error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.results
+}
+
+func Test_AsyncPublisher_SendsAndFlushes(t *testing.T) {
+	r := require.New(t)
+
+	// sends are held until three of them are in flight at once
+	gate := make(chan struct{})
+	started := make(chan string, 7)
+	var inFlight, maxInFlight atomic.Int32
+	sut, err := NewAsyncPublisher(AsyncPublisherOpts{
+		Publisher: publisherFunc(func(ctx context.Context, m Message) (*SendResponse, error) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				prev := maxInFlight.Load()
+				if n <= prev || maxInFlight.CompareAndSwap(prev, n) {
+					break
+				}
+			}
+			started <- m.Message
+			<-gate
+			if m.Message == "bad" {
+				return nil, errors.New("boom")
+			}
+			return &SendResponse{MessageEvent: MessageEvent{ID: m.Message}}, nil
+		}),
+		Workers: 3,
+	})
+	r.NoError(err)
+
+	var results asyncResults
+	for _, msg := range []string{"a", "b", "bad", "c", "d", "e"} {
+		r.NoError(sut.Enqueue(context.Background(), Message{Message: msg}, results.callback(msg)))
+	}
+	r.NoError(sut.Enqueue(context.Background(), Message{Message: "f"}, nil))
+
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	r.Equal(int32(3), inFlight.Load())
+	close(gate)
+
+	r.NoError(sut.Flush(context.Background()))
+	got := results.get()
+	r.Len(got, 6)
+	r.EqualError(got["bad"], "boom")
+	for _, msg := range []string{"a", "b", "c", "d", "e"} {
+		r.NoError(got[msg])
+	}
+	r.Equal(int32(3), maxInFlight.Load(), "no more than Workers sends should be in flight")
+
+	r.NoError(sut.Close(context.Background()))
+	r.ErrorIs(sut.Enqueue(context.Background(), Message{}, nil), ErrPublisherClosed)
+}
+
+func Test_AsyncPublisher_Overflow(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		policy    OverflowPolicy
+		wantErr   error
+		delivered []string
+		dropped   []string
+	}{
+		{name: "drop oldest", policy: OverflowDropOldest, delivered: []string{"1", "3", "4"}, dropped: []string{"2"}},
+		{name: "drop newest", policy: OverflowDropNewest, delivered: []string{"1", "2", "3"}, dropped: []string{"4"}},
+		{name: "error", policy: OverflowError, wantErr: ErrQueueFull, delivered: []string{"1", "2", "3"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			gate := make(chan struct{})
+			started := make(chan string, 4)
+			sut, err := NewAsyncPublisher(AsyncPublisherOpts{
+				Publisher: gatedPublisher(gate, started),
+				QueueSize: 2,
+				Overflow:  tc.policy,
+			})
+			r.NoError(err)
+
+			var results asyncResults
+			r.NoError(sut.Enqueue(context.Background(), Message{Message: "1"}, results.callback("1")))
+			r.Equal("1", <-started) // the worker holds "1", leaving the queue empty
+			r.NoError(sut.Enqueue(context.Background(), Message{Message: "2"}, results.callback("2")))
+			r.NoError(sut.Enqueue(context.Background(), Message{Message: "3"}, results.callback("3")))
+
+			err = sut.Enqueue(context.Background(), Message{Message: "4"}, results.callback("4"))
+			if tc.wantErr != nil {
+				r.ErrorIs(err, tc.wantErr)
+			} else {
+				r.NoError(err)
+			}
+
+			close(gate)
+			r.NoError(sut.Close(context.Background()))
+
+			got := results.get()
+			r.Len(got, len(tc.delivered)+len(tc.dropped))
+			for _, msg := range tc.delivered {
+				r.NoError(got[msg], msg)
+			}
+			for _, msg := range tc.dropped {
+				r.ErrorIs(got[msg], ErrMessageDropped, msg)
+			}
+		})
+	}
+}
+
+func Test_AsyncPublisher_OverflowBlock(t *testing.T) {
+	r := require.New(t)
+
+	gate := make(chan struct{})
+	started := make(chan string, 3)
+	sut, err := NewAsyncPublisher(AsyncPublisherOpts{
+		Publisher: gatedPublisher(gate, started),
+		QueueSize: 1,
+	})
+	r.NoError(err)
+
+	r.NoError(sut.Enqueue(context.Background(), Message{Message: "1"}, nil))
+	<-started
+	r.NoError(sut.Enqueue(context.Background(), Message{Message: "2"}, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	r.ErrorIs(sut.Enqueue(ctx, Message{Message: "3"}, nil), context.DeadlineExceeded)
+
+	enqueued := make(chan error)
+	go func() {
+		enqueued <- sut.Enqueue(context.Background(), Message{Message: "3"}, nil)
+	}()
+	select {
+	case <-enqueued:
+		r.Fail("Enqueue should block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(gate)
+	r.NoError(<-enqueued)
+	r.NoError(sut.Flush(context.Background()))
+	r.NoError(sut.Close(context.Background()))
+}
+
+func Test_AsyncPublisher_CloseDrainsQueue(t *testing.T) {
+	r := require.New(t)
+
+	var sent atomic.Int32
+	sut, err := NewAsyncPublisher(AsyncPublisherOpts{
+		Publisher: publisherFunc(func(ctx context.Context, m Message) (*SendResponse, error) {
+			time.Sleep(time.Millisecond)
+			sent.Add(1)
+			return &SendResponse{}, nil
+		}),
+	})
+	r.NoError(err)
+
+	for i := 0; i < 10; i++ {
+		r.NoError(sut.Enqueue(context.Background(), Message{}, nil))
+	}
+	r.NoError(sut.Close(context.Background()))
+	r.Equal(int32(10), sent.Load())
+}
+
+func Test_AsyncPublisher_CloseCancelsOnTimeout(t *testing.T) {
+	r := require.New(t)
+
+	started := make(chan string, 2)
+	sut, err := NewAsyncPublisher(AsyncPublisherOpts{
+		Publisher: gatedPublisher(make(chan struct{}), started),
+	})
+	r.NoError(err)
+
+	var results asyncResults
+	r.NoError(sut.Enqueue(context.Background(), Message{Message: "1"}, results.callback("1")))
+	r.NoError(sut.Enqueue(context.Background(), Message{Message: "2"}, results.callback("2")))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	r.ErrorIs(sut.Close(ctx), context.DeadlineExceeded)
+
+	got := results.get()
+	r.Len(got, 2)
+	assert.ErrorIs(t, got["1"], context.Canceled)
+	assert.ErrorIs(t, got["2"], context.Canceled)
+}
@@ -0,0 +1,170 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// AttachmentPublisher is implemented by publishers which can upload files as attachments.
+// The Publisher returned by NewPublisher implements it:
+//
+//	ap := pub.(gotfy.AttachmentPublisher)
+//	resp, err := ap.SendAttachment(ctx, msg, gotfy.AttachmentUpload{Path: "build.log"})
+type AttachmentPublisher interface {
+	// SendAttachment publishes m with the given file attached. The server's response describes
+	// the stored attachment in SendResponse.Attachment.
+	//
+	// The file is streamed as the request body, so the request is never retried.
+	// See: https://docs.ntfy.sh/publish/#attach-local-file
+	SendAttachment(ctx context.Context, m Message, a AttachmentUpload) (*SendResponse, error)
+}
+
+// DefaultAttachmentFilename is used for attachments uploaded from an io.Reader without a Filename.
+const DefaultAttachmentFilename = "attachment"
+
+// AttachmentUpload is a file to upload along with a message. Exactly one of Body and Path must be set.
+type AttachmentUpload struct {
+	// Body provides the contents of the attachment.
+	Body io.Reader
+
+	// Path is the file to attach.
+	Path string
+
+	// Filename is the name the attachment is shown with. Defaults to the base name of Path,
+	// or to DefaultAttachmentFilename. A filename is always sent, as Ntfy would otherwise
+	// take a short text file to be the message itself.
+	Filename string
+
+	// Size is the length of Body in bytes, sent as the Content-Length, if known. If zero, the
+	// body is sent in chunks. The size of the file at Path is determined automatically.
+	Size int64
+
+	// Progress, if set, is called as the attachment is uploaded with the number of bytes sent so far
+	// and the total size, which is -1 if it isn't known.
+	Progress func(sent, total int64)
+}
+
+// SendAttachment publishes m with a file attached, using a PUT request to the message's topic.
+// The message's fields are carried in headers, as the body holds the file.
+func (p *publisher) SendAttachment(ctx context.Context, m Message, a AttachmentUpload) (*SendResponse, error) {
+	if (a.Body == nil) == (a.Path == "") {
+		return nil, errors.New("exactly one of the attachment's Body and Path must be set")
+	}
+	if m.AttachURL != nil {
+		return nil, errors.New("a message cannot both upload an attachment and attach a URL")
+	}
+	if m.AttachURLFilename != "" {
+		// it would be sent as X-Filename, alongside the upload's own name
+		return nil, errors.New("an uploaded attachment is named by AttachmentUpload.Filename, not AttachURLFilename")
+	}
+	if m.Topic == "" {
+		return nil, errors.New("topic must not be empty")
+	}
+	if err := m.checkSchedule(p.now(), p.maxDelay); err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	body, size, filename := a.Body, a.Size, a.Filename
+	if a.Path != "" {
+		f, err := os.Open(a.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open attachment: %w", err)
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open attachment: %w", err)
+		}
+		body, size = f, fi.Size()
+		if filename == "" {
+			filename = filepath.Base(a.Path)
+		}
+	}
+	if filename == "" {
+		filename = DefaultAttachmentFilename
+	}
+
+	total := size
+	if total <= 0 {
+		total = -1
+	}
+	if a.Progress != nil {
+		body = &progressReader{r: body, total: total, progress: a.Progress}
+	}
+
+	if p.limiter != nil {
+		if err := p.limiter.wait(ctx, &m); err != nil {
+			return nil, fmt.Errorf("failed to send message: %w", err)
+		}
+	}
+
+	req, err := p.newAttachmentRequest(ctx, &m, body, size, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.do(req)
+	if err != nil {
+		var retryable *retryableError
+		if errors.As(err, &retryable) {
+			err = retryable.err
+		}
+		return nil, err
+	}
+	resp.Attempts = 1
+	resp.Server = p.server.String()
+	return resp, nil
+}
+
+// newAttachmentRequest builds the HTTP request which uploads body as an attachment to m.
+func (p *publisher) newAttachmentRequest(ctx context.Context, m *Message, body io.Reader, size int64, filename string) (*http.Request, error) {
+	h, err := messageHeaders(m)
+	if err != nil {
+		return nil, err
+	}
+	// the request body is the attachment, so the message goes in a header
+	if m.Message != "" {
+		h.Set("X-Message", headerValue(m.Message))
+	}
+
+	endpoint := p.server.JoinPath(m.Topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if size > 0 {
+		req.ContentLength = size
+	}
+
+	req.Header = p.headers.Clone()
+	req.Header.Del("Content-Type")
+	for name, values := range h {
+		req.Header[name] = values
+	}
+	req.Header.Set("Filename", headerValue(filename))
+
+	return req, nil
+}
+
+// progressReader reports the progress of reading r.
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if n > 0 {
+		r.sent += int64(n)
+		r.progress(r.sent, r.total)
+	}
+	return n, err
+}
@@ -0,0 +1,142 @@
+package gotfy
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAttachmentResponse = `{"id":"bUhbhgmmbeW0","time":1685150791,"expires":1685193991,"event":"message","topic":"builds",` +
+	`"message":"Build failed","attachment":{"name":"build.log","type":"text/plain; charset=utf-8","size":11,` +
+	`"expires":1685172391,"url":"https://ntfy.example.com/file/bUhbhgmmbeW0.txt"}}`
+
+func newAttachmentTestPublisher(t *testing.T, handler http.HandlerFunc) AttachmentPublisher {
+	return NewPublisher(PublisherOpts{Server: newTestServer(t, handler), Auth: AccessToken("tk_0123456789")}).(AttachmentPublisher)
+}
+
+func Test_Publisher_SendAttachmentFromPath(t *testing.T) {
+	r := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "build.log")
+	r.NoError(os.WriteFile(path, []byte("hello world"), 0o600))
+
+	var dec mime.WordDecoder
+	sut := newAttachmentTestPublisher(t, func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPut, req.Method)
+		assert.Equal(t, "/builds", req.URL.Path)
+		assert.Equal(t, "Bearer tk_0123456789", req.Header.Get("Authorization"))
+		assert.Equal(t, "build.log", req.Header.Get("Filename"))
+		assert.Equal(t, "Build failed", req.Header.Get("X-Message"))
+		assert.Equal(t, "warning,ci", req.Header.Get("X-Tags"))
+		assert.Equal(t, "4", req.Header.Get("X-Priority"))
+		assert.Empty(t, req.Header.Get("Content-Type"))
+		assert.Equal(t, int64(11), req.ContentLength)
+
+		title, err := dec.DecodeHeader(req.Header.Get("X-Title"))
+		assert.NoError(t, err)
+		assert.Equal(t, "Übersetzung fehlgeschlagen ❌", title)
+		assert.NotEqual(t, title, req.Header.Get("X-Title"))
+
+		body, _ := io.ReadAll(req.Body)
+		assert.Equal(t, "hello world", string(body))
+		_, _ = io.WriteString(w, testAttachmentResponse)
+	})
+
+	var progress [][2]int64
+	resp, err := sut.SendAttachment(context.Background(), Message{
+		Topic:    "builds",
+		Message:  "Build failed",
+		Title:    "Übersetzung fehlgeschlagen ❌",
+		Tags:     []string{"warning", "ci"},
+		Priority: PriorityHigh,
+	}, AttachmentUpload{
+		Path:     path,
+		Progress: func(sent, total int64) { progress = append(progress, [2]int64{sent, total}) },
+	})
+	r.NoError(err)
+	r.Equal(1, resp.Attempts)
+	r.NotNil(resp.Attachment)
+	r.Equal("build.log", resp.Attachment.Name)
+	r.Equal("text/plain; charset=utf-8", resp.Attachment.Type)
+	r.Equal(int64(11), resp.Attachment.Size)
+	r.Equal(int64(1685172391), resp.Attachment.Expires.Unix())
+	r.Equal("https://ntfy.example.com/file/bUhbhgmmbeW0.txt", resp.Attachment.URL)
+
+	r.NotEmpty(progress)
+	r.Equal([2]int64{11, 11}, progress[len(progress)-1])
+}
+
+func Test_Publisher_SendAttachmentFromReader(t *testing.T) {
+	r := require.New(t)
+
+	sut := newAttachmentTestPublisher(t, func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, DefaultAttachmentFilename, req.Header.Get("Filename"))
+		assert.Equal(t, "https://example.com", req.Header.Get("X-Click"))
+		assert.Equal(t, []string{"chunked"}, req.TransferEncoding)
+
+		body, _ := io.ReadAll(req.Body)
+		assert.Equal(t, "hello world", string(body))
+		_, _ = io.WriteString(w, testAttachmentResponse)
+	})
+
+	var last [2]int64
+	_, err := sut.SendAttachment(context.Background(), Message{
+		Topic:    "builds",
+		ClickURL: &url.URL{Scheme: "https", Host: "example.com"},
+	}, AttachmentUpload{
+		Body:     io.MultiReader(strings.NewReader("hello "), strings.NewReader("world")),
+		Progress: func(sent, total int64) { last = [2]int64{sent, total} },
+	})
+	r.NoError(err)
+	r.Equal([2]int64{11, -1}, last)
+}
+
+func Test_Publisher_SendAttachmentTooLarge(t *testing.T) {
+	r := require.New(t)
+
+	sut := newAttachmentTestPublisher(t, func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "screenshot.png", req.Header.Get("Filename"))
+		assert.Equal(t, int64(4), req.ContentLength)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		_, _ = io.WriteString(w, `{"code":41301,"http":413,"error":"attachment too large, or bandwidth limit reached"}`)
+	})
+
+	_, err := sut.SendAttachment(context.Background(), Message{Topic: "builds"}, AttachmentUpload{
+		Body:     strings.NewReader("\x89PNG"),
+		Filename: "screenshot.png",
+		Size:     4,
+	})
+	r.ErrorIs(err, ErrAttachmentTooLarge)
+}
+
+func Test_Publisher_SendAttachmentInvalid(t *testing.T) {
+	r := require.New(t)
+	sut := NewPublisher(PublisherOpts{HttpClient: &FakeHttpClient{}}).(AttachmentPublisher)
+
+	_, err := sut.SendAttachment(context.Background(), Message{Topic: "builds"}, AttachmentUpload{})
+	r.EqualError(err, "exactly one of the attachment's Body and Path must be set")
+
+	_, err = sut.SendAttachment(context.Background(), Message{
+		Topic:     "builds",
+		AttachURL: &url.URL{Scheme: "https", Host: "example.com"},
+	}, AttachmentUpload{Body: strings.NewReader("x")})
+	r.EqualError(err, "a message cannot both upload an attachment and attach a URL")
+
+	_, err = sut.SendAttachment(context.Background(), Message{
+		Topic:             "builds",
+		AttachURLFilename: "build.log",
+	}, AttachmentUpload{Body: strings.NewReader("x")})
+	r.EqualError(err, "an uploaded attachment is named by AttachmentUpload.Filename, not AttachURLFilename")
+
+	_, err = sut.SendAttachment(context.Background(), Message{Topic: "builds"}, AttachmentUpload{Path: filepath.Join(t.TempDir(), "missing")})
+	r.ErrorIs(err, os.ErrNotExist)
+}
@@ -0,0 +1,79 @@
+package gotfy
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff describes an exponential backoff with random jitter, used between reconnect
+// and retry attempts.
+type Backoff struct {
+	Initial    time.Duration // Delay before the first retry. Defaults to DefaultBackoff.Initial.
+	Max        time.Duration // Upper bound for any single delay. Defaults to DefaultBackoff.Max.
+	Multiplier float64       // Factor by which the delay grows with each attempt. Defaults to DefaultBackoff.Multiplier.
+	Jitter     float64       // Fraction (0-1) of each delay that is randomized. Zero disables jitter.
+}
+
+// DefaultBackoff is used when no Backoff is configured.
+var DefaultBackoff = Backoff{
+	Initial:    time.Second,
+	Max:        time.Minute,
+	Multiplier: 2,
+	Jitter:     0.5,
+}
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Delay returns how long to wait before the given attempt, counting from zero.
+// Jitter only ever shortens the delay, so the result never exceeds Max.
+func (b Backoff) Delay(attempt int) time.Duration {
+	initial, maxDelay, mult := b.Initial, b.Max, b.Multiplier
+	if initial <= 0 {
+		initial = DefaultBackoff.Initial
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultBackoff.Max
+	}
+	if mult < 1 {
+		mult = DefaultBackoff.Multiplier
+	}
+	if attempt < 0 {
+		attempt = 0
+	}
+
+	d := float64(initial) * math.Pow(mult, float64(attempt))
+	if d > float64(maxDelay) {
+		d = float64(maxDelay)
+	}
+
+	if j := b.Jitter; j > 0 {
+		if j > 1 {
+			j = 1
+		}
+		jitterMu.Lock()
+		f := jitterRand.Float64()
+		jitterMu.Unlock()
+		d -= d * j * f
+	}
+
+	return time.Duration(d)
+}
+
+// sleepContext waits for d to elapse, returning early with ctx's error if it is cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,28 @@
+package gotfy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	r := require.New(t)
+
+	b := Backoff{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2}
+	r.Equal(time.Second, b.Delay(0))
+	r.Equal(2*time.Second, b.Delay(1))
+	r.Equal(8*time.Second, b.Delay(3))
+	r.Equal(10*time.Second, b.Delay(4))
+	r.Equal(10*time.Second, b.Delay(100))
+
+	r.Equal(DefaultBackoff.Initial, Backoff{}.Delay(0))
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := b.Delay(4)
+		r.GreaterOrEqual(d, 5*time.Second)
+		r.LessOrEqual(d, 10*time.Second)
+	}
+}
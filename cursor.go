@@ -0,0 +1,120 @@
+package gotfy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Cursor records the last message processed from a topic.
+type Cursor struct {
+	ID   string    `json:"id"`   // ID of the last processed message.
+	Time time.Time `json:"time"` // Time the last processed message was published.
+}
+
+// CursorStore persists the position of subscriptions across restarts.
+// Implementations must be safe for concurrent use.
+type CursorStore interface {
+	// Load returns the cursor saved for the given topic, or nil if there is none.
+	Load(topic string) (*Cursor, error)
+	// Save records c as the cursor for the given topic.
+	Save(topic string, c Cursor) error
+}
+
+// NewMemoryCursorStore returns a CursorStore which keeps cursors in memory only.
+func NewMemoryCursorStore() CursorStore {
+	return &memoryCursorStore{cursors: make(map[string]Cursor)}
+}
+
+type memoryCursorStore struct {
+	mu      sync.Mutex
+	cursors map[string]Cursor
+}
+
+func (m *memoryCursorStore) Load(topic string) (*Cursor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.cursors[topic]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (m *memoryCursorStore) Save(topic string, c Cursor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.cursors[topic] = c
+	return nil
+}
+
+// NewFileCursorStore returns a CursorStore which keeps cursors for all topics in a single JSON file.
+// The file is replaced atomically on every save, so a crash never leaves it partially written.
+func NewFileCursorStore(path string) (CursorStore, error) {
+	f := &fileCursorStore{
+		path:    path,
+		cursors: make(map[string]Cursor),
+	}
+
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cursor file: %w", err)
+	}
+
+	if len(buf) > 0 {
+		if err := json.Unmarshal(buf, &f.cursors); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal cursor file from JSON: %w", err)
+		}
+	}
+
+	return f, nil
+}
+
+type fileCursorStore struct {
+	path string
+
+	mu      sync.Mutex
+	cursors map[string]Cursor
+}
+
+func (f *fileCursorStore) Load(topic string) (*Cursor, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.cursors[topic]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (f *fileCursorStore) Save(topic string, c Cursor) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	prev, hadPrev := f.cursors[topic]
+	f.cursors[topic] = c
+
+	buf, err := json.Marshal(f.cursors)
+	if err == nil {
+		err = writeFileAtomic(f.path, buf, 0o600)
+	}
+	if err != nil {
+		if hadPrev {
+			f.cursors[topic] = prev
+		} else {
+			delete(f.cursors, topic)
+		}
+		return fmt.Errorf("failed to save cursor: %w", err)
+	}
+
+	return nil
+}
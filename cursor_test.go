@@ -0,0 +1,85 @@
+package gotfy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCursorStore_Persists(t *testing.T) {
+	r := require.New(t)
+	path := filepath.Join(t.TempDir(), "cursors.json")
+
+	store, err := NewFileCursorStore(path)
+	r.NoError(err)
+
+	c, err := store.Load("mytopic")
+	r.NoError(err)
+	r.Nil(c)
+
+	ts := time.Unix(1685150791, 0).UTC()
+	r.NoError(store.Save("mytopic", Cursor{ID: "a1", Time: ts}))
+	r.NoError(store.Save("other", Cursor{ID: "b1", Time: ts}))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	r.NoError(err)
+	r.Len(entries, 1, "temporary files should not be left behind")
+
+	reopened, err := NewFileCursorStore(path)
+	r.NoError(err)
+	c, err = reopened.Load("mytopic")
+	r.NoError(err)
+	r.Equal(&Cursor{ID: "a1", Time: ts}, c)
+}
+
+func Test_Subscriber_ResumesFromCursor(t *testing.T) {
+	r := require.New(t)
+	store := NewMemoryCursorStore()
+	r.NoError(store.Save("mytopic", Cursor{ID: "m1", Time: time.Unix(1685150791, 0)}))
+
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "m1", req.URL.Query().Get("since"))
+		_, _ = fmt.Fprintln(w, `{"id":"m2","time":1685150792,"event":"message","topic":"mytopic","message":"hi"}`)
+		_, _ = fmt.Fprintln(w, `{"id":"m3","time":1685150793,"event":"message","topic":"mytopic","message":"hi"}`)
+	}, SubscriberOpts{Cursors: store, DisableReconnect: true})
+
+	err := sut.Subscribe(context.Background(), "mytopic", func(e *MessageEvent) {
+		if e.ID == "m2" {
+			assert.NoError(t, sut.Ack(e))
+		}
+	})
+	r.ErrorIs(err, ErrStreamClosed)
+
+	c, err := store.Load("mytopic")
+	r.NoError(err)
+	r.Equal("m2", c.ID)
+	r.Equal(int64(1685150792), c.Time.Unix())
+
+	// acknowledging an older message does not move the cursor back
+	r.NoError(sut.Ack(&MessageEvent{ID: "m1", Event: EventMessage, Topic: "mytopic", Time: UnixTime{time.Unix(1685150791, 0)}}))
+	c, err = store.Load("mytopic")
+	r.NoError(err)
+	r.Equal("m2", c.ID)
+}
+
+func Test_Subscriber_ResumesMultipleTopicsByTime(t *testing.T) {
+	r := require.New(t)
+	store := NewMemoryCursorStore()
+	r.NoError(store.Save("a", Cursor{ID: "m1", Time: time.Unix(1685150795, 0)}))
+	r.NoError(store.Save("b", Cursor{ID: "m2", Time: time.Unix(1685150791, 0)}))
+
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/a,b,c/json", req.URL.Path)
+		assert.Equal(t, "1685150791", req.URL.Query().Get("since"))
+	}, SubscriberOpts{Cursors: store, DisableReconnect: true})
+
+	err := sut.Subscribe(context.Background(), "a,b,c", func(e *MessageEvent) {})
+	r.ErrorIs(err, ErrStreamClosed)
+}
@@ -0,0 +1,87 @@
+package gotfy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// maxErrorBodySize bounds how much of an error response is read.
+	maxErrorBodySize = 64 << 10
+	// maxErrorTextSize bounds how much of a non-JSON error response is used as the error message.
+	maxErrorTextSize = 512
+)
+
+// APIError is an error response from a Ntfy server.
+// Use errors.Is to compare it against the sentinel errors below, or errors.As to inspect it.
+// See: https://github.com/binwiederhier/ntfy/blob/main/server/errors.go
+type APIError struct {
+	Code     int    `json:"code"`           // Ntfy error code, e.g. 42901; zero if the server sent none.
+	HTTPCode int    `json:"http"`           // HTTP status code of the response.
+	Message  string `json:"error"`          // Description of the error.
+	Link     string `json:"link,omitempty"` // Link to relevant documentation, if any.
+}
+
+func (e *APIError) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("HTTP %d: %s", e.HTTPCode, e.Message)
+	}
+	return fmt.Sprintf("HTTP %d: %s (ntfy error %d)", e.HTTPCode, e.Message, e.Code)
+}
+
+// Is reports whether target is an *APIError with the same ntfy error code.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code != 0 && t.Code == e.Code
+}
+
+// Sentinel errors for common ntfy error codes, for use with errors.Is.
+var (
+	ErrUnauthorized         = &APIError{Code: 40101, HTTPCode: http.StatusUnauthorized, Message: "unauthorized"}
+	ErrForbidden            = &APIError{Code: 40301, HTTPCode: http.StatusForbidden, Message: "forbidden"}
+	ErrAttachmentTooLarge   = &APIError{Code: 41301, HTTPCode: http.StatusRequestEntityTooLarge, Message: "attachment too large, or bandwidth limit reached"}
+	ErrMessageTooLarge      = &APIError{Code: 41303, HTTPCode: http.StatusRequestEntityTooLarge, Message: "JSON body too large"}
+	ErrRateLimited          = &APIError{Code: 42901, HTTPCode: http.StatusTooManyRequests, Message: "limit reached: too many requests"}
+	ErrEmailQuotaExceeded   = &APIError{Code: 42902, HTTPCode: http.StatusTooManyRequests, Message: "limit reached: too many emails"}
+	ErrMessageQuotaExceeded = &APIError{Code: 42908, HTTPCode: http.StatusTooManyRequests, Message: "limit reached: daily message quota reached"}
+	ErrCallQuotaExceeded    = &APIError{Code: 42910, HTTPCode: http.StatusTooManyRequests, Message: "limit reached: daily phone call quota reached"}
+)
+
+// newAPIError builds an APIError from a non-2xx response, preferring the JSON error body ntfy sends.
+// It does not close the response body.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{}
+
+	var body []byte
+	if resp.Body != nil {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	}
+	if err := json.Unmarshal(body, apiErr); err != nil {
+		*apiErr = APIError{}
+		if text := strings.TrimSpace(string(body)); len(text) <= maxErrorTextSize {
+			apiErr.Message = text
+		}
+	}
+
+	if apiErr.HTTPCode == 0 {
+		apiErr.HTTPCode = resp.StatusCode
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return apiErr
+}
+
+// isPermanent reports whether err is a client error that repeating the same request will not fix.
+func isPermanent(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.HTTPCode >= 400 && apiErr.HTTPCode < 500 && !isTransient(apiErr)
+}
@@ -0,0 +1,90 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func errorResponse(status int, body string) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	}
+}
+
+func Test_Publisher_APIError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   int
+		body     string
+		sentinel error
+		expected APIError
+	}{
+		{
+			name:     "unauthorized",
+			status:   401,
+			body:     `{"code":40101,"http":401,"error":"unauthorized","link":"https://ntfy.sh/docs/publish/#authentication"}`,
+			sentinel: ErrUnauthorized,
+			expected: APIError{Code: 40101, HTTPCode: 401, Message: "unauthorized", Link: "https://ntfy.sh/docs/publish/#authentication"},
+		},
+		{
+			name:     "rate limited",
+			status:   429,
+			body:     `{"code":42901,"http":429,"error":"limit reached: too many requests"}`,
+			sentinel: ErrRateLimited,
+			expected: APIError{Code: 42901, HTTPCode: 429, Message: "limit reached: too many requests"},
+		},
+		{
+			name:     "email quota",
+			status:   429,
+			body:     `{"code":42902,"http":429,"error":"limit reached: too many emails"}`,
+			sentinel: ErrEmailQuotaExceeded,
+			expected: APIError{Code: 42902, HTTPCode: 429, Message: "limit reached: too many emails"},
+		},
+		{
+			name:     "message too large",
+			status:   413,
+			body:     `{"code":41303,"http":413,"error":"JSON body too large"}`,
+			sentinel: ErrMessageTooLarge,
+			expected: APIError{Code: 41303, HTTPCode: 413, Message: "JSON body too large"},
+		},
+		{
+			name:     "not JSON",
+			status:   502,
+			body:     "upstream unavailable\n",
+			expected: APIError{HTTPCode: 502, Message: "upstream unavailable"},
+		},
+		{
+			name:     "empty body",
+			status:   503,
+			expected: APIError{HTTPCode: 503, Message: "Service Unavailable"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			sut := NewPublisher(PublisherOpts{
+				HttpClient: &FakeHttpClient{Response: errorResponse(tc.status, tc.body)},
+			})
+
+			_, err := sut.Send(context.Background(), Message{Topic: "mytopic"})
+
+			var apiErr *APIError
+			r.True(errors.As(err, &apiErr), "unexpected error: %v", err)
+			r.Equal(tc.expected, *apiErr)
+			if tc.sentinel != nil {
+				r.ErrorIs(err, tc.sentinel)
+			}
+			r.False(errors.Is(err, ErrForbidden))
+		})
+	}
+}
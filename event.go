@@ -0,0 +1,82 @@
+package gotfy
+
+import "encoding/json"
+
+// EventType identifies the kind of event received from a Ntfy server.
+// See: https://docs.ntfy.sh/subscribe/api/#json-message-format
+type EventType string
+
+const (
+	EventOpen        EventType = "open"         // Sent when a subscription is established.
+	EventKeepalive   EventType = "keepalive"    // Sent periodically to keep the connection alive.
+	EventMessage     EventType = "message"      // Sent when a notification is published to the topic.
+	EventPollRequest EventType = "poll_request" // Sent when a notification should be fetched via polling (iOS only).
+)
+
+// MessageEvent is a single event received from a Ntfy server.
+// Only events of type EventMessage carry notification content;
+// EventOpen and EventKeepalive only signal that the connection is alive.
+// See: https://docs.ntfy.sh/subscribe/api/#json-message-format
+type MessageEvent struct {
+	ID       string    `json:"id"`                 // Randomly chosen message identifier.
+	Time     UnixTime  `json:"time"`               // Message date time.
+	Expires  UnixTime  `json:"expires,omitempty"`  // Time at which the message will be deleted from the server cache.
+	Event    EventType `json:"event"`              // Type of the event.
+	Topic    string    `json:"topic"`              // Comma-separated list of topics the message is associated with.
+	Message  string    `json:"message,omitempty"`  // Message body.
+	Title    string    `json:"title,omitempty"`    // Message title.
+	Tags     []string  `json:"tags,omitempty"`     // List of tags that may or not map to emojis.
+	Priority Priority  `json:"priority,omitempty"` // Message priority with 1=min, 3=default and 5=max.
+	Click    string    `json:"click,omitempty"`    // Website opened when the notification is clicked.
+	Icon     string    `json:"icon,omitempty"`     // URL to use as notification icon.
+	PollID   string    `json:"poll_id,omitempty"`  // ID of the message to fetch, for EventPollRequest events.
+
+	Actions     ActionButtons `json:"actions,omitempty"`      // Action buttons attached to the notification.
+	Attachment  *Attachment   `json:"attachment,omitempty"`   // File attached to the message, if any.
+	ContentType string        `json:"content_type,omitempty"` // "text/markdown" if the message is to be rendered as Markdown.
+	Encoding    string        `json:"encoding,omitempty"`     // "base64" if Message holds a base64-encoded binary body.
+}
+
+// Attachment describes a file attached to a message.
+// See: https://docs.ntfy.sh/subscribe/api/#json-message-format
+type Attachment struct {
+	Name    string   `json:"name"`              // Name of the attachment; can be overridden with the Filename header.
+	Type    string   `json:"type,omitempty"`    // MIME type of the attachment; only set for files uploaded to the server.
+	Size    int64    `json:"size,omitempty"`    // Size of the attachment in bytes; only set for files uploaded to the server.
+	Expires UnixTime `json:"expires,omitempty"` // Time at which the attachment will be deleted from the server.
+	URL     string   `json:"url"`               // URL of the attachment.
+}
+
+// MarshalJSON marshals the event into the format it is received in. Expires is left out
+// when it is zero, as it is for events which aren't messages.
+// It has a value receiver so that both MessageEvent and *MessageEvent values use it.
+func (e MessageEvent) MarshalJSON() ([]byte, error) {
+	type event MessageEvent // without this method
+	aux := struct {
+		event
+		Expires *UnixTime `json:"expires,omitempty"`
+	}{event: event(e)}
+	if !e.Expires.IsZero() {
+		aux.Expires = &e.Expires
+	}
+	return json.Marshal(aux)
+}
+
+// MarshalJSON marshals the attachment into the format it is received in. Expires is left out
+// when it is zero, as it is for attachments linked by URL.
+func (a Attachment) MarshalJSON() ([]byte, error) {
+	type attachment Attachment // without this method
+	aux := struct {
+		attachment
+		Expires *UnixTime `json:"expires,omitempty"`
+	}{attachment: attachment(a)}
+	if !a.Expires.IsZero() {
+		aux.Expires = &a.Expires
+	}
+	return json.Marshal(aux)
+}
+
+// IsMessage reports whether the event carries notification content.
+func (e *MessageEvent) IsMessage() bool {
+	return e.Event == EventMessage
+}
@@ -0,0 +1,117 @@
+package gotfy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const fullEventJSON = `{
+	"id": "sPs71M8A2T",
+	"time": 1643935928,
+	"expires": 1643936928,
+	"event": "message",
+	"topic": "mytopic",
+	"message": "Disk space is low at 5.1 GB",
+	"title": "Low disk space alert",
+	"tags": ["warning", "skull"],
+	"priority": 4,
+	"click": "https://homecam.mynet.lan/incident/1234",
+	"icon": "https://example.com/icon.png",
+	"content_type": "text/markdown",
+	"actions": [
+		{"action": "view", "label": "Admin panel", "url": "https://filesrv.lan/admin", "clear": true},
+		{"action": "http", "label": "Close door", "url": "https://api.example.com/door", "method": "PUT", "headers": {"X-Door": "front"}, "body": "close"}
+	],
+	"attachment": {
+		"name": "flower.jpg",
+		"type": "image/jpeg",
+		"size": 15285,
+		"expires": 1643946728,
+		"url": "https://ntfy.sh/file/sPs71M8A2T.jpg"
+	}
+}`
+
+func TestMessageEvent_UnmarshalJSON(t *testing.T) {
+	r := require.New(t)
+
+	var e MessageEvent
+	r.NoError(json.Unmarshal([]byte(fullEventJSON), &e))
+
+	r.Equal("sPs71M8A2T", e.ID)
+	r.Equal(int64(1643935928), e.Time.Unix())
+	r.Equal(int64(1643936928), e.Expires.Unix())
+	r.Equal(EventMessage, e.Event)
+	r.Equal("Low disk space alert", e.Title)
+	r.Equal([]string{Warning, Skull}, e.Tags)
+	r.Equal(PriorityHigh, e.Priority)
+	r.Equal("https://homecam.mynet.lan/incident/1234", e.Click)
+	r.Equal("https://example.com/icon.png", e.Icon)
+	r.Equal("text/markdown", e.ContentType)
+
+	r.Len(e.Actions, 2)
+	view, ok := e.Actions[0].(*ViewAction)
+	r.True(ok)
+	r.Equal("Admin panel", view.Label)
+	r.Equal("https://filesrv.lan/admin", view.Link.String())
+	r.True(view.Clear)
+	httpAction, ok := e.Actions[1].(*HttpAction[string])
+	r.True(ok)
+	r.Equal("PUT", httpAction.Method)
+	r.Equal("close", httpAction.Body)
+
+	r.Equal("flower.jpg", e.Attachment.Name)
+	r.Equal("image/jpeg", e.Attachment.Type)
+	r.Equal(int64(15285), e.Attachment.Size)
+	r.Equal(int64(1643946728), e.Attachment.Expires.Unix())
+	r.Equal("https://ntfy.sh/file/sPs71M8A2T.jpg", e.Attachment.URL)
+}
+
+func TestMessageEvent_RoundTrip(t *testing.T) {
+	r := require.New(t)
+
+	var original MessageEvent
+	r.NoError(json.Unmarshal([]byte(fullEventJSON), &original))
+
+	buf, err := json.Marshal(&original)
+	r.NoError(err)
+	r.JSONEq(fullEventJSON, string(buf))
+
+	var decoded MessageEvent
+	r.NoError(json.Unmarshal(buf, &decoded))
+	r.Equal(original, decoded)
+}
+
+func TestMessageEvent_RoundTripWithoutExpiry(t *testing.T) {
+	for _, original := range []string{
+		`{"id":"a1","time":1685150791,"event":"open","topic":"mytopic"}`,
+		`{"id":"a2","time":1685150821,"event":"keepalive","topic":"mytopic"}`,
+		`{"id":"a3","time":1685150830,"event":"message","topic":"mytopic","message":"hi","attachment":{"name":"flower.jpg","url":"https://example.com/flower.jpg"}}`,
+	} {
+		r := require.New(t)
+
+		var e MessageEvent
+		r.NoError(json.Unmarshal([]byte(original), &e))
+
+		byPointer, err := json.Marshal(&e)
+		r.NoError(err)
+		r.JSONEq(original, string(byPointer))
+
+		byValue, err := json.Marshal(e)
+		r.NoError(err)
+		r.Equal(byPointer, byValue)
+	}
+}
+
+func TestSendResponse_UnmarshalJSON(t *testing.T) {
+	r := require.New(t)
+
+	var resp SendResponse
+	r.NoError(json.Unmarshal([]byte(fullEventJSON), &resp))
+	r.Equal("sPs71M8A2T", resp.ID)
+	r.Equal(EventMessage, resp.Event)
+	r.Equal("Low disk space alert", resp.Title)
+	r.Len(resp.Actions, 2)
+	r.Equal("flower.jpg", resp.Attachment.Name)
+}
@@ -0,0 +1,220 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults used when the corresponding FailoverOpts field is zero.
+const (
+	DefaultFailureThreshold = 3
+	DefaultCircuitCooldown  = 30 * time.Second
+)
+
+// ErrNoServerAvailable is returned by a failover publisher when every server's circuit is open.
+var ErrNoServerAvailable = errors.New("no server available")
+
+// FailoverServer is one of the servers a failover publisher sends to.
+type FailoverServer struct {
+	// PublisherOpts configures how messages are sent to this server, including its Authorization.
+	PublisherOpts
+
+	// Topics maps the topics of messages to the topics used on this server.
+	// Topics which aren't mapped are used as they are.
+	Topics map[string]string
+}
+
+// FailoverOpts contains the configuration options for a new failover Publisher.
+type FailoverOpts struct {
+	// Servers are tried in order until one of them accepts the message.
+	Servers []FailoverServer
+
+	// FailureThreshold is the number of consecutive failures after which a server is skipped
+	// until Cooldown has passed. If zero, DefaultFailureThreshold is used.
+	// Failures which don't reflect on the server's health, such as a rejected message, don't count.
+	FailureThreshold int
+
+	// Cooldown is how long a server is skipped once FailureThreshold is reached, after which
+	// a single message is sent to it to find out whether it has recovered.
+	// If zero, DefaultCircuitCooldown is used.
+	Cooldown time.Duration
+}
+
+// FailoverError is returned when no server accepted a message. It holds the error from each server tried.
+type FailoverError struct {
+	Errors []error
+}
+
+func (e *FailoverError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "all servers failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *FailoverError) Unwrap() []error {
+	return e.Errors
+}
+
+// Is reports whether any of the errors matches target. errors.Is only follows Unwrap() []error
+// from Go 1.20, so this makes it see the errors from each server on earlier releases, too.
+func (e *FailoverError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches target, as errors.As does, for the same reason as Is.
+func (e *FailoverError) As(target any) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+type failoverTarget struct {
+	name      string
+	publisher Publisher
+	topics    map[string]string
+	breaker   *circuitBreaker
+}
+
+type failoverPublisher struct {
+	targets []*failoverTarget
+}
+
+// NewFailoverPublisher creates a Publisher which sends each message to the first server able to
+// accept it. The URL of that server is reported in SendResponse.Server.
+func NewFailoverPublisher(opts FailoverOpts) (Publisher, error) {
+	if len(opts.Servers) == 0 {
+		return nil, errors.New("at least one server is required")
+	}
+
+	threshold := opts.FailureThreshold
+	if threshold <= 0 {
+		threshold = DefaultFailureThreshold
+	}
+	cooldown := opts.Cooldown
+	if cooldown <= 0 {
+		cooldown = DefaultCircuitCooldown
+	}
+
+	retv := &failoverPublisher{}
+	for _, s := range opts.Servers {
+		server := serverOrDefault(s.Server)
+		retv.targets = append(retv.targets, &failoverTarget{
+			name:      server.String(),
+			publisher: NewPublisher(s.PublisherOpts),
+			topics:    s.Topics,
+			breaker:   newCircuitBreaker(threshold, cooldown),
+		})
+	}
+	return retv, nil
+}
+
+func (p *failoverPublisher) Send(ctx context.Context, m Message) (*SendResponse, error) {
+	var errs []error
+	for _, t := range p.targets {
+		if !t.breaker.allow() {
+			continue
+		}
+
+		tm := m
+		if topic, ok := t.topics[m.Topic]; ok {
+			tm.Topic = topic
+		}
+
+		resp, err := t.publisher.Send(ctx, tm)
+		switch {
+		case err == nil:
+			t.breaker.record(true)
+			resp.Server = t.name
+			return resp, nil
+		case ctx.Err() != nil:
+			t.breaker.release()
+			return nil, err
+		case isScheduleError(err):
+			// the message wasn't sent; another server might allow it to be scheduled further ahead
+			t.breaker.release()
+		case isPermanent(err):
+			// the server is up, but rejected the message; another one might accept it
+			t.breaker.record(true)
+		default:
+			t.breaker.record(false)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", t.name, err))
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("failed to send message: %w", ErrNoServerAvailable)
+	}
+	return nil, &FailoverError{Errors: errs}
+}
+
+// circuitBreaker tracks the health of a server. After threshold consecutive failures it opens,
+// rejecting requests until cooldown has passed; then it lets a single request through, which
+// closes it again on success.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool // a request is testing whether the server has recovered
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a request may be sent. Every allowed request must be followed by
+// a call to record or release.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record reports the outcome of an allowed request.
+func (b *circuitBreaker) record(healthy bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if healthy {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// release ends an allowed request whose outcome says nothing about the server's health.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
@@ -0,0 +1,181 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failoverTestServer serves publish requests with the status returned by status,
+// counting the requests it receives.
+type failoverTestServer struct {
+	url      *url.URL
+	requests atomic.Int32
+	status   atomic.Int32
+}
+
+func newFailoverTestServer(t *testing.T, check func(req *http.Request, body string)) *failoverTestServer {
+	s := &failoverTestServer{}
+	s.status.Store(http.StatusOK)
+
+	s.url = newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.requests.Add(1)
+		body, _ := io.ReadAll(req.Body)
+		if check != nil {
+			check(req, string(body))
+		}
+
+		switch status := int(s.status.Load()); status {
+		case http.StatusOK:
+			_, _ = io.WriteString(w, `{"id":"bUhbhgmmbeW0","time":1685150791,"event":"message","topic":"mytopic"}`)
+		case http.StatusBadRequest:
+			w.WriteHeader(status)
+			_, _ = io.WriteString(w, `{"code":40001,"http":400,"error":"invalid request"}`)
+		default:
+			w.WriteHeader(status)
+		}
+	}))
+	return s
+}
+
+func Test_FailoverPublisher(t *testing.T) {
+	r := require.New(t)
+
+	primary := newFailoverTestServer(t, func(req *http.Request, body string) {
+		assert.Equal(t, "Bearer tk_primary", req.Header.Get("Authorization"))
+		assert.Equal(t, `{"topic":"alerts","message":"hi"}`, body)
+	})
+	fallback := newFailoverTestServer(t, func(req *http.Request, body string) {
+		assert.Equal(t, "Bearer tk_fallback", req.Header.Get("Authorization"))
+		assert.Equal(t, `{"topic":"alerts-3f9a","message":"hi"}`, body)
+	})
+
+	sut, err := NewFailoverPublisher(FailoverOpts{
+		Servers: []FailoverServer{
+			{PublisherOpts: PublisherOpts{Server: primary.url, Auth: AccessToken("tk_primary")}},
+			{
+				PublisherOpts: PublisherOpts{Server: fallback.url, Auth: AccessToken("tk_fallback")},
+				Topics:        map[string]string{"alerts": "alerts-3f9a"},
+			},
+		},
+		FailureThreshold: 2,
+		Cooldown:         time.Minute,
+	})
+	r.NoError(err)
+
+	now := time.Unix(1685150791, 0)
+	for _, target := range sut.(*failoverPublisher).targets {
+		target.breaker.now = func() time.Time { return now }
+	}
+	send := func() *SendResponse {
+		resp, err := sut.Send(context.Background(), Message{Topic: "alerts", Message: "hi"})
+		r.NoError(err)
+		return resp
+	}
+
+	r.Equal(primary.url.String(), send().Server)
+
+	// the primary fails until its circuit opens
+	primary.status.Store(http.StatusBadGateway)
+	r.Equal(fallback.url.String(), send().Server)
+	r.Equal(fallback.url.String(), send().Server)
+	r.Equal(int32(3), primary.requests.Load())
+
+	r.Equal(fallback.url.String(), send().Server)
+	r.Equal(int32(3), primary.requests.Load(), "primary should be skipped while its circuit is open")
+
+	// after the cooldown, a single message probes the primary
+	now = now.Add(time.Minute)
+	primary.status.Store(http.StatusOK)
+	r.Equal(primary.url.String(), send().Server)
+	r.Equal(primary.url.String(), send().Server)
+	r.Equal(int32(5), primary.requests.Load())
+	r.Equal(int32(3), fallback.requests.Load())
+}
+
+func Test_FailoverPublisher_RejectedMessagesKeepCircuitClosed(t *testing.T) {
+	r := require.New(t)
+
+	primary := newFailoverTestServer(t, nil)
+	primary.status.Store(http.StatusBadRequest)
+	fallback := newFailoverTestServer(t, nil)
+	fallback.status.Store(http.StatusBadRequest)
+
+	sut, err := NewFailoverPublisher(FailoverOpts{
+		Servers: []FailoverServer{
+			{PublisherOpts: PublisherOpts{Server: primary.url}},
+			{PublisherOpts: PublisherOpts{Server: fallback.url}},
+		},
+		FailureThreshold: 1,
+	})
+	r.NoError(err)
+
+	for i := 0; i < 3; i++ {
+		_, err = sut.Send(context.Background(), Message{Topic: "mytopic"})
+		var failoverErr *FailoverError
+		r.True(errors.As(err, &failoverErr))
+		r.Len(failoverErr.Errors, 2)
+		r.ErrorIs(err, &APIError{Code: 40001})
+		r.True(failoverErr.Is(&APIError{Code: 40001}))
+		var apiErr *APIError
+		r.True(errors.As(err, &apiErr))
+		r.Equal(40001, apiErr.Code)
+		r.True(failoverErr.As(&apiErr))
+		r.Equal(400, apiErr.HTTPCode)
+	}
+	r.Equal(int32(3), primary.requests.Load())
+}
+
+func Test_FailoverPublisher_AllCircuitsOpen(t *testing.T) {
+	r := require.New(t)
+
+	primary := newFailoverTestServer(t, nil)
+	primary.status.Store(http.StatusServiceUnavailable)
+
+	sut, err := NewFailoverPublisher(FailoverOpts{
+		Servers:          []FailoverServer{{PublisherOpts: PublisherOpts{Server: primary.url}}},
+		FailureThreshold: 1,
+	})
+	r.NoError(err)
+
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic"})
+	r.Contains(err.Error(), "all servers failed: "+primary.url.String()+": failed to send message: HTTP 503")
+
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic"})
+	r.ErrorIs(err, ErrNoServerAvailable)
+	r.Equal(int32(1), primary.requests.Load())
+}
+
+func Test_FailoverPublisher_ScheduleErrorsKeepCircuitClosed(t *testing.T) {
+	r := require.New(t)
+
+	primary := newFailoverTestServer(t, nil)
+	fallback := newFailoverTestServer(t, nil)
+
+	sut, err := NewFailoverPublisher(FailoverOpts{
+		Servers: []FailoverServer{
+			{PublisherOpts: PublisherOpts{Server: primary.url}},
+			{PublisherOpts: PublisherOpts{Server: fallback.url, MaxDelay: 7 * 24 * time.Hour}},
+		},
+		FailureThreshold: 1,
+	})
+	r.NoError(err)
+
+	// only the fallback allows delivery this far ahead
+	resp, err := sut.Send(context.Background(), Message{Topic: "mytopic", Delay: 5 * 24 * time.Hour})
+	r.NoError(err)
+	r.Equal(fallback.url.String(), resp.Server)
+	r.Zero(primary.requests.Load())
+
+	resp, err = sut.Send(context.Background(), Message{Topic: "mytopic"})
+	r.NoError(err)
+	r.Equal(primary.url.String(), resp.Server, "primary's circuit should still be closed")
+}
@@ -0,0 +1,187 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// SuccessPolicy decides whether a message sent by a fan-out publisher counts as delivered.
+type SuccessPolicy int
+
+const (
+	SuccessAll    SuccessPolicy = iota // Every target must accept the message.
+	SuccessAny                         // At least one target must accept the message.
+	SuccessQuorum                      // FanoutOpts.Quorum targets must accept the message.
+)
+
+// ErrNotDelivered is returned by a fan-out publisher when too few targets accepted a message
+// to satisfy its SuccessPolicy.
+var ErrNotDelivered = errors.New("message not delivered")
+
+// FanoutTarget is one of the destinations a fan-out publisher sends every message to.
+type FanoutTarget struct {
+	// PublisherOpts configures how messages are sent to this target.
+	PublisherOpts
+
+	// Topic, if set, replaces the topic of every message sent to this target.
+	Topic string
+}
+
+// FanoutOpts contains the configuration options for a new FanoutPublisher.
+type FanoutOpts struct {
+	Targets []FanoutTarget
+
+	// Policy decides whether a message counts as delivered. Defaults to SuccessAll.
+	Policy SuccessPolicy
+
+	// Quorum is the number of targets which must accept a message under SuccessQuorum.
+	// If zero, a majority of the targets is required.
+	Quorum int
+}
+
+// FanoutPublisher sends every message to several targets concurrently.
+type FanoutPublisher interface {
+	// Send sends m to every target, and returns the response from the first target, in the order
+	// they were configured, which accepted it. It fails with ErrNotDelivered if the SuccessPolicy
+	// isn't met.
+	Send(ctx context.Context, m Message) (*SendResponse, error)
+
+	// SendAll sends m to every target, and reports the outcome for each. The report is returned
+	// even if the SuccessPolicy isn't met, along with an error matching ErrNotDelivered.
+	SendAll(ctx context.Context, m Message) (*FanoutReport, error)
+}
+
+// FanoutReport holds the outcome of sending a message to each target of a FanoutPublisher.
+type FanoutReport struct {
+	Results   []FanoutResult // One result per target, in the order the targets were configured.
+	Succeeded int            // Number of targets which accepted the message.
+	Delivered bool           // Whether the SuccessPolicy was met.
+}
+
+// FanoutResult is the outcome of sending a message to a single target.
+type FanoutResult struct {
+	Server   string        // URL of the target's server.
+	Topic    string        // Topic the message was sent to.
+	Response *SendResponse // The server's response, if it accepted the message.
+	Err      error         // Why the message wasn't accepted, if it wasn't.
+}
+
+type fanoutTarget struct {
+	server    string
+	topic     string
+	publisher Publisher
+}
+
+type fanoutPublisher struct {
+	targets  []fanoutTarget
+	required int // number of targets which must accept a message
+}
+
+// NewFanoutPublisher creates a FanoutPublisher for the given targets.
+func NewFanoutPublisher(opts FanoutOpts) (FanoutPublisher, error) {
+	n := len(opts.Targets)
+	if n == 0 {
+		return nil, errors.New("at least one target is required")
+	}
+
+	retv := &fanoutPublisher{}
+	switch opts.Policy {
+	case SuccessAll:
+		retv.required = n
+	case SuccessAny:
+		retv.required = 1
+	case SuccessQuorum:
+		retv.required = opts.Quorum
+		if retv.required == 0 {
+			retv.required = n/2 + 1
+		}
+		if retv.required < 0 || retv.required > n {
+			return nil, fmt.Errorf("quorum of %d is impossible with %d targets", opts.Quorum, n)
+		}
+	default:
+		return nil, fmt.Errorf("unknown success policy %d", opts.Policy)
+	}
+
+	for _, t := range opts.Targets {
+		server := serverOrDefault(t.Server)
+		retv.targets = append(retv.targets, fanoutTarget{
+			server:    server.String(),
+			topic:     t.Topic,
+			publisher: NewPublisher(t.PublisherOpts),
+		})
+	}
+	return retv, nil
+}
+
+func (p *fanoutPublisher) Send(ctx context.Context, m Message) (*SendResponse, error) {
+	report, err := p.SendAll(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, res := range report.Results {
+		if res.Err == nil {
+			return res.Response, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to send message: %w", ErrNotDelivered) // not reached
+}
+
+func (p *fanoutPublisher) SendAll(ctx context.Context, m Message) (*FanoutReport, error) {
+	report := &FanoutReport{Results: make([]FanoutResult, len(p.targets))}
+
+	var wg sync.WaitGroup
+	for i, t := range p.targets {
+		tm := m
+		if t.topic != "" {
+			tm.Topic = t.topic
+		}
+		report.Results[i] = FanoutResult{Server: t.server, Topic: tm.Topic}
+
+		wg.Add(1)
+		go func(res *FanoutResult, publisher Publisher) {
+			defer wg.Done()
+			res.Response, res.Err = publisher.Send(ctx, tm)
+		}(&report.Results[i], t.publisher)
+	}
+	wg.Wait()
+
+	var failed error
+	for _, res := range report.Results {
+		if res.Err == nil {
+			report.Succeeded++
+		} else if failed == nil {
+			failed = res.Err
+		}
+	}
+	report.Delivered = report.Succeeded >= p.required
+
+	if !report.Delivered {
+		return report, &notDeliveredError{
+			msg:   fmt.Sprintf("%d of %d targets accepted it, %d required", report.Succeeded, len(p.targets), p.required),
+			first: failed,
+		}
+	}
+	return report, nil
+}
+
+// notDeliveredError matches ErrNotDelivered, and wraps the error of the first target which failed,
+// so that errors.Is and errors.As find both. Go 1.19 only allows one %w in fmt.Errorf.
+type notDeliveredError struct {
+	msg   string
+	first error
+}
+
+func (e *notDeliveredError) Error() string {
+	return fmt.Sprintf("%s: %s; first error: %v", ErrNotDelivered, e.msg, e.first)
+}
+
+func (e *notDeliveredError) Is(target error) bool {
+	return target == ErrNotDelivered
+}
+
+func (e *notDeliveredError) Unwrap() error {
+	return e.first
+}
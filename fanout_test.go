@@ -0,0 +1,128 @@
+package gotfy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FanoutPublisher_SendAll(t *testing.T) {
+	r := require.New(t)
+
+	release := make(chan struct{})
+	var servers []*failoverTestServer
+	for _, topic := range []string{"alerts", "alerts", "critical"} {
+		topic := topic
+		servers = append(servers, newFailoverTestServer(t, func(req *http.Request, body string) {
+			assert.Equal(t, `{"topic":"`+topic+`","message":"disk full"}`, body)
+			<-release // every target must be sent to concurrently for this to return
+		}))
+	}
+	servers[1].status.Store(http.StatusBadGateway)
+	go func() {
+		for _, s := range servers {
+			for s.requests.Load() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		close(release)
+	}()
+
+	sut, err := NewFanoutPublisher(FanoutOpts{
+		Targets: []FanoutTarget{
+			{PublisherOpts: PublisherOpts{Server: servers[0].url}},
+			{PublisherOpts: PublisherOpts{Server: servers[1].url}},
+			{PublisherOpts: PublisherOpts{Server: servers[2].url}, Topic: "critical"},
+		},
+		Policy: SuccessQuorum,
+	})
+	r.NoError(err)
+
+	report, err := sut.SendAll(context.Background(), Message{Topic: "alerts", Message: "disk full"})
+	r.NoError(err)
+	r.True(report.Delivered)
+	r.Equal(2, report.Succeeded)
+	r.Len(report.Results, 3)
+
+	r.Equal(servers[0].url.String(), report.Results[0].Server)
+	r.Equal("alerts", report.Results[0].Topic)
+	r.NoError(report.Results[0].Err)
+	r.Equal("bUhbhgmmbeW0", report.Results[0].Response.ID)
+
+	r.Nil(report.Results[1].Response)
+	r.EqualError(report.Results[1].Err, "failed to send message: HTTP 502: Bad Gateway")
+
+	r.Equal("critical", report.Results[2].Topic)
+	r.NoError(report.Results[2].Err)
+}
+
+func Test_FanoutPublisher_Policies(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		policy    SuccessPolicy
+		quorum    int
+		failing   int
+		delivered bool
+	}{
+		{name: "all, none failing", policy: SuccessAll, delivered: true},
+		{name: "all, one failing", policy: SuccessAll, failing: 1},
+		{name: "any, three failing", policy: SuccessAny, failing: 3, delivered: true},
+		{name: "any, all failing", policy: SuccessAny, failing: 4},
+		{name: "majority, two failing", policy: SuccessQuorum, failing: 2},
+		{name: "majority, one failing", policy: SuccessQuorum, failing: 1, delivered: true},
+		{name: "quorum of two, two failing", policy: SuccessQuorum, quorum: 2, failing: 2, delivered: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			var targets []FanoutTarget
+			var first *failoverTestServer
+			for i := 0; i < 4; i++ {
+				s := newFailoverTestServer(t, nil)
+				if i < tc.failing {
+					s.status.Store(http.StatusServiceUnavailable)
+				} else if first == nil {
+					first = s
+				}
+				targets = append(targets, FanoutTarget{PublisherOpts: PublisherOpts{Server: s.url}})
+			}
+
+			sut, err := NewFanoutPublisher(FanoutOpts{Targets: targets, Policy: tc.policy, Quorum: tc.quorum})
+			r.NoError(err)
+
+			report, err := sut.SendAll(context.Background(), Message{Topic: "mytopic"})
+			r.Equal(tc.delivered, report.Delivered)
+			r.Equal(4-tc.failing, report.Succeeded)
+
+			resp, sendErr := sut.Send(context.Background(), Message{Topic: "mytopic"})
+			if tc.delivered {
+				r.NoError(err)
+				r.NoError(sendErr)
+				r.Equal(first.url.String(), resp.Server)
+			} else {
+				r.ErrorIs(err, ErrNotDelivered)
+				r.ErrorIs(sendErr, ErrNotDelivered)
+
+				// the first target's error can be inspected, too
+				var apiErr *APIError
+				r.ErrorAs(err, &apiErr)
+				r.Equal(http.StatusServiceUnavailable, apiErr.HTTPCode)
+			}
+		})
+	}
+}
+
+func Test_NewFanoutPublisher_InvalidQuorum(t *testing.T) {
+	r := require.New(t)
+
+	_, err := NewFanoutPublisher(FanoutOpts{
+		Targets: []FanoutTarget{{}, {}},
+		Policy:  SuccessQuorum,
+		Quorum:  3,
+	})
+	r.EqualError(err, "quorum of 3 is impossible with 2 targets")
+}
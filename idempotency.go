@@ -0,0 +1,99 @@
+package gotfy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// FingerprintTagPrefix prefixes the tag which carries a message's fingerprint by default.
+const FingerprintTagPrefix = "gotfy-fp-"
+
+// DefaultReconcileWindow is used when IdempotencyOpts.Window is zero.
+const DefaultReconcileWindow = time.Minute
+
+// IdempotencyOpts configures duplicate-safe retries.
+//
+// Each message is given a random fingerprint before it is sent. When an attempt fails in a way
+// that leaves its outcome unknown (the connection failed or timed out, or the server returned a 5xx
+// error after the request reached it), the publisher polls the topic for a message carrying that
+// fingerprint before retrying. If one is found, the original attempt succeeded and is not repeated.
+// If the topic cannot be polled, the message is retried as usual, favouring duplicates over losses.
+//
+// Reconciling requires read access to the topic, and is only useful together with a RetryPolicy.
+type IdempotencyOpts struct {
+	// Window is how far back to look for the original message. If zero, DefaultReconcileWindow is used.
+	Window time.Duration
+
+	// Embed adds the fingerprint to the message. By default, it adds a tag made of
+	// FingerprintTagPrefix and the fingerprint, which is displayed along with the notification.
+	Embed func(m *Message, fingerprint string)
+
+	// Match reports whether e carries the fingerprint, and must agree with Embed.
+	// By default, it looks for the tag added by the default Embed.
+	Match func(e *MessageEvent, fingerprint string) bool
+}
+
+func (o *IdempotencyOpts) embed(m *Message, fingerprint string) {
+	if o.Embed != nil {
+		o.Embed(m, fingerprint)
+		return
+	}
+
+	// copy, so that the caller's slice is never modified
+	tags := make([]string, 0, len(m.Tags)+1)
+	tags = append(tags, m.Tags...)
+	m.Tags = append(tags, FingerprintTagPrefix+fingerprint)
+}
+
+func (o *IdempotencyOpts) match(e *MessageEvent, fingerprint string) bool {
+	if o.Match != nil {
+		return o.Match(e, fingerprint)
+	}
+
+	for _, tag := range e.Tags {
+		if tag == FingerprintTagPrefix+fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// newFingerprint returns a random identifier for a message.
+func newFingerprint() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate message fingerprint: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// reconcile polls m's topic for a message with the given fingerprint, returning it if found.
+func (p *publisher) reconcile(ctx context.Context, m *Message, fingerprint string) *MessageEvent {
+	window := p.idempotency.Window
+	if window <= 0 {
+		window = DefaultReconcileWindow
+	}
+
+	opts := PollOpts{
+		Since:     SinceDuration(window),
+		Scheduled: true,
+	}
+	if p.idempotency.Match == nil {
+		opts.Tags = []string{FingerprintTagPrefix + fingerprint}
+	}
+
+	events, err := poll(ctx, p.httpClient, p.server, p.headers, m.Topic, opts)
+	if err != nil {
+		return nil
+	}
+
+	for _, e := range events {
+		if p.idempotency.match(e, fingerprint) {
+			return e
+		}
+	}
+	return nil
+}
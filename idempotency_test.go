@@ -0,0 +1,192 @@
+package gotfy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTopic is a minimal ntfy topic which remembers every message published to it.
+type fakeTopic struct {
+	mu       sync.Mutex
+	requests int
+	messages []Message
+	polls    []string
+
+	// publish, if set, is called with the number of each publish request, counting from one.
+	// It calls store to keep the message, and reports whether the usual response should be sent;
+	// if not, it is responsible for the response itself.
+	publish func(n int, w http.ResponseWriter, store func()) (respond bool)
+}
+
+func (f *fakeTopic) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodGet {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.polls = append(f.polls, req.URL.RawQuery)
+		tag := req.URL.Query().Get("tags")
+		for i, m := range f.messages {
+			if tag != "" && !containsString(m.Tags, tag) {
+				continue
+			}
+			b, _ := json.Marshal(MessageEvent{ID: fmt.Sprintf("m%d", i), Event: EventMessage, Topic: m.Topic, Title: m.Title, Tags: m.Tags})
+			_, _ = w.Write(append(b, '\n'))
+		}
+		return
+	}
+
+	var m Message
+	if err := json.NewDecoder(req.Body).Decode(&m); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.requests++
+	n := f.requests
+	hook := f.publish
+	f.mu.Unlock()
+
+	id := -1
+	store := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		id = len(f.messages)
+		f.messages = append(f.messages, m)
+	}
+
+	respond := true
+	if hook != nil {
+		respond = hook(n, w, store)
+	} else {
+		store()
+	}
+
+	if respond {
+		_, _ = fmt.Fprintf(w, `{"id":"m%d","time":1685150791,"event":"message","topic":%q}`, id, m.Topic)
+	}
+}
+
+func Test_Publisher_ReconcilesTimedOutAttempt(t *testing.T) {
+	r := require.New(t)
+
+	topic := &fakeTopic{}
+	release := make(chan struct{})
+	defer close(release)
+	topic.publish = func(n int, w http.ResponseWriter, store func()) bool {
+		store()
+		if n == 1 {
+			<-release // stored, but the response never arrives in time
+			return false
+		}
+		return true
+	}
+
+	sut := newIdempotencyTestPublisher(t, topic, &IdempotencyOpts{})
+
+	tags := []string{"warning"}
+	resp, err := sut.Send(context.Background(), Message{Topic: "mytopic", Message: "hi", Tags: tags[:1:1]})
+	r.NoError(err)
+	r.True(resp.Reconciled)
+	r.Equal("m0", resp.ID)
+	r.Equal(1, resp.Attempts)
+	r.Equal([]string{"warning"}, tags)
+
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+	r.Len(topic.messages, 1, "message must not be published twice")
+	r.Len(topic.messages[0].Tags, 2)
+	r.Equal("warning", topic.messages[0].Tags[0])
+	r.True(strings.HasPrefix(topic.messages[0].Tags[1], FingerprintTagPrefix))
+	r.Len(topic.polls, 1)
+	r.Contains(topic.polls[0], "poll=1")
+	r.Contains(topic.polls[0], "since=1m0s")
+	r.Contains(topic.polls[0], "tags="+topic.messages[0].Tags[1])
+}
+
+func Test_Publisher_RetriesWhenNotReconciled(t *testing.T) {
+	r := require.New(t)
+
+	topic := &fakeTopic{}
+	topic.publish = func(n int, w http.ResponseWriter, store func()) bool {
+		if n == 1 {
+			// the server failed before storing the message
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return false
+		}
+		store()
+		return true
+	}
+
+	sut := newIdempotencyTestPublisher(t, topic, &IdempotencyOpts{})
+
+	resp, err := sut.Send(context.Background(), Message{Topic: "mytopic", Message: "hi"})
+	r.NoError(err)
+	r.False(resp.Reconciled)
+	r.Equal(2, resp.Attempts)
+
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+	r.Len(topic.messages, 1)
+	r.Len(topic.polls, 1)
+}
+
+func Test_Publisher_ReconcilesWithCustomFingerprint(t *testing.T) {
+	r := require.New(t)
+
+	topic := &fakeTopic{}
+	topic.publish = func(n int, w http.ResponseWriter, store func()) bool {
+		store()
+		w.WriteHeader(http.StatusBadGateway)
+		return false
+	}
+
+	sut := newIdempotencyTestPublisher(t, topic, &IdempotencyOpts{
+		Window: 5 * time.Minute,
+		Embed: func(m *Message, fingerprint string) {
+			m.Title = m.Title + " [" + fingerprint + "]"
+		},
+		Match: func(e *MessageEvent, fingerprint string) bool {
+			return strings.HasSuffix(e.Title, " ["+fingerprint+"]")
+		},
+	})
+
+	resp, err := sut.Send(context.Background(), Message{Topic: "mytopic", Title: "Backup"})
+	r.NoError(err)
+	r.True(resp.Reconciled)
+	r.True(strings.HasPrefix(resp.Title, "Backup ["))
+
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+	r.Len(topic.messages, 1)
+	r.Empty(topic.messages[0].Tags)
+	r.Contains(topic.polls[0], "since=5m0s")
+	r.NotContains(topic.polls[0], "tags=")
+}
+
+func newIdempotencyTestPublisher(t *testing.T, handler http.Handler, opts *IdempotencyOpts) Publisher {
+	p := newRetryTestPublisher(t, handler.ServeHTTP, &RetryPolicy{
+		MaxAttempts:    3,
+		Backoff:        fastBackoff,
+		AttemptTimeout: 200 * time.Millisecond,
+	}).(*publisher)
+	p.idempotency = opts
+	return p
+}
+
+func containsString(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+package gotfy
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MarkdownInline is a fragment of inline Markdown: text, emphasis, links or code.
+// The functions returning it escape what they are given, so that user-supplied text can't
+// introduce formatting of its own. Converting a string to MarkdownInline uses it as-is.
+type MarkdownInline string
+
+// markdownEscaper escapes the characters which start or end inline formatting, or headings.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `~`, `\~`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `&`, `\&`, `#`, `\#`, `|`, `\|`,
+)
+
+// markdownBullet and markdownOrdered match text at the start of a line which would begin
+// a list, or turn the line before it into a heading.
+var (
+	markdownBullet  = regexp.MustCompile(`(?m)^([ \t]*)([-+=])`)
+	markdownOrdered = regexp.MustCompile(`(?m)^([ \t]*)(\d+)([.)])`)
+)
+
+var markdownNewlines = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+// MarkdownText returns s as Markdown which renders as exactly s, escaping anything that
+// would otherwise be taken as formatting.
+func MarkdownText(s string) MarkdownInline {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = markdownEscaper.Replace(s)
+	s = markdownBullet.ReplaceAllString(s, `${1}\${2}`)
+	s = markdownOrdered.ReplaceAllString(s, `${1}${2}\${3}`)
+	return MarkdownInline(s)
+}
+
+// MarkdownJoin concatenates fragments of inline Markdown.
+func MarkdownJoin(parts ...MarkdownInline) MarkdownInline {
+	var b strings.Builder
+	for _, p := range parts {
+		b.WriteString(string(p))
+	}
+	return MarkdownInline(b.String())
+}
+
+// MarkdownBold renders text in bold.
+func MarkdownBold(text ...MarkdownInline) MarkdownInline {
+	return markdownEmphasis("**", text)
+}
+
+// MarkdownItalic renders text in italics.
+func MarkdownItalic(text ...MarkdownInline) MarkdownInline {
+	return markdownEmphasis("*", text)
+}
+
+// markdownEmphasis wraps text in delim. Surrounding whitespace is kept outside of the delimiters,
+// as Markdown doesn't recognize them next to it.
+func markdownEmphasis(delim string, text []MarkdownInline) MarkdownInline {
+	s := string(MarkdownJoin(text...))
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return MarkdownInline(s)
+	}
+	lead := s[:strings.Index(s, trimmed)]
+	trail := s[len(lead)+len(trimmed):]
+	return MarkdownInline(lead + delim + trimmed + delim + trail)
+}
+
+// MarkdownCode renders s as inline code. Code spans can't hold line breaks, so they become spaces.
+func MarkdownCode(s string) MarkdownInline {
+	s = markdownNewlines.Replace(s)
+	if s == "" {
+		return ""
+	}
+
+	// the content is delimited by a longer run of backticks than it contains, and padded with
+	// a space where it would otherwise be misread or lose one
+	fence := strings.Repeat("`", longestRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") ||
+		(strings.HasPrefix(s, " ") && strings.HasSuffix(s, " ") && strings.Trim(s, " ") != "") {
+		s = " " + s + " "
+	}
+	return MarkdownInline(fence + s + fence)
+}
+
+// MarkdownLink renders text as a link to u. If text is empty, the URL is shown instead;
+// if u is nil, text is rendered without a link.
+func MarkdownLink(u *url.URL, text ...MarkdownInline) MarkdownInline {
+	label := MarkdownJoin(text...)
+	if u == nil {
+		return label
+	}
+	if label == "" {
+		label = MarkdownText(u.String())
+	}
+	dest := strings.NewReplacer(`\`, `\\`, `<`, `\<`, `>`, `\>`).Replace(u.String())
+	return MarkdownInline("[" + string(label) + "](<" + dest + ">)")
+}
+
+// MarkdownBuilder assembles a Markdown message body from blocks: headings, paragraphs, lists,
+// code blocks and tables. Their text is given as MarkdownInline, so that it's escaped:
+//
+//	var md gotfy.MarkdownBuilder
+//	md.Heading(2, gotfy.MarkdownText("Deployed "+service)).
+//		Paragraph(gotfy.MarkdownText("Commit "), gotfy.MarkdownCode(sha), gotfy.MarkdownText(" by "), gotfy.MarkdownBold(gotfy.MarkdownText(author)))
+//	m := gotfy.Message{Topic: "deploys", Message: md.String(), Markdown: true}
+//
+// The zero value is an empty document.
+// See: https://docs.ntfy.sh/publish/#markdown-formatting
+type MarkdownBuilder struct {
+	blocks   []string
+	lastList byte // marker of the list at the end of the document, if there is one
+}
+
+// String returns the document.
+func (b *MarkdownBuilder) String() string {
+	return strings.Join(b.blocks, "\n\n")
+}
+
+func (b *MarkdownBuilder) add(block string, list byte) *MarkdownBuilder {
+	b.blocks = append(b.blocks, block)
+	b.lastList = list
+	return b
+}
+
+// Heading adds a heading of the given level, from 1 to 6. Line breaks in text become spaces.
+func (b *MarkdownBuilder) Heading(level int, text ...MarkdownInline) *MarkdownBuilder {
+	if level < 1 {
+		level = 1
+	}
+	if level > 6 {
+		level = 6
+	}
+	s := strings.TrimSpace(markdownNewlines.Replace(string(MarkdownJoin(text...))))
+	return b.add(strings.Repeat("#", level)+" "+s, 0)
+}
+
+// Paragraph adds a paragraph of text. Empty paragraphs are left out.
+func (b *MarkdownBuilder) Paragraph(text ...MarkdownInline) *MarkdownBuilder {
+	s := trimLines(string(MarkdownJoin(text...)), "")
+	if strings.TrimSpace(s) == "" {
+		return b
+	}
+	return b.add(s, 0)
+}
+
+// BulletList adds a list with an item for each of items.
+func (b *MarkdownBuilder) BulletList(items ...MarkdownInline) *MarkdownBuilder {
+	// a list right after another of the same kind would continue it, unless it uses another marker
+	marker := byte('-')
+	if b.lastList == marker {
+		marker = '*'
+	}
+	return b.list(items, marker, func(int) string { return string(marker) + " " })
+}
+
+// NumberedList adds a list numbered from 1, with an item for each of items.
+func (b *MarkdownBuilder) NumberedList(items ...MarkdownInline) *MarkdownBuilder {
+	marker := byte('.')
+	if b.lastList == marker {
+		marker = ')'
+	}
+	return b.list(items, marker, func(i int) string { return strconv.Itoa(i+1) + string(marker) + " " })
+}
+
+func (b *MarkdownBuilder) list(items []MarkdownInline, marker byte, prefix func(int) string) *MarkdownBuilder {
+	if len(items) == 0 {
+		return b
+	}
+	lines := make([]string, len(items))
+	for i, item := range items {
+		p := prefix(i)
+		lines[i] = p + trimLines(string(item), strings.Repeat(" ", len(p)))
+	}
+	return b.add(strings.Join(lines, "\n"), marker)
+}
+
+// CodeBlock adds a block of code, highlighted as the given language if it's not empty.
+func (b *MarkdownBuilder) CodeBlock(language, code string) *MarkdownBuilder {
+	code = strings.TrimSuffix(strings.ReplaceAll(code, "\r\n", "\n"), "\n")
+
+	n := longestRun(code, '`') + 1
+	if n < 3 {
+		n = 3
+	}
+	fence := strings.Repeat("`", n)
+
+	info := ""
+	if fields := strings.Fields(strings.ReplaceAll(language, "`", "")); len(fields) > 0 {
+		info = fields[0]
+	}
+	return b.add(fence+info+"\n"+code+"\n"+fence, 0)
+}
+
+// Table adds a table with the given header cells. Each row is padded or cut to the same number
+// of cells as the header. Line breaks in cells become spaces. A table without a header is left out.
+func (b *MarkdownBuilder) Table(header []MarkdownInline, rows ...[]MarkdownInline) *MarkdownBuilder {
+	if len(header) == 0 {
+		return b
+	}
+
+	row := func(cells []MarkdownInline) string {
+		var sb strings.Builder
+		sb.WriteString("|")
+		for i := range header {
+			var cell string
+			if i < len(cells) {
+				cell = markdownCell(cells[i])
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+		return sb.String()
+	}
+
+	lines := []string{row(header), "|" + strings.Repeat(" --- |", len(header))}
+	for _, r := range rows {
+		lines = append(lines, row(r))
+	}
+	return b.add(strings.Join(lines, "\n"), 0)
+}
+
+// markdownCell formats s as the content of a table cell, in which every pipe must be escaped,
+// even within code.
+func markdownCell(s MarkdownInline) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range strings.TrimSpace(markdownNewlines.Replace(string(s))) {
+		if r == '|' && !escaped {
+			b.WriteRune('\\')
+		}
+		escaped = r == '\\' && !escaped
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// trimLines removes the leading whitespace of each line of s, which Markdown ignores but which
+// could turn it into a code block, and indents every line after the first by indent.
+func trimLines(s, indent string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " \t")
+		if i > 0 && line != "" {
+			line = indent + line
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// longestRun returns the length of the longest run of r in s.
+func longestRun(s string, r rune) int {
+	longest, n := 0, 0
+	for _, c := range s {
+		if c != r {
+			n = 0
+			continue
+		}
+		n++
+		if n > longest {
+			longest = n
+		}
+	}
+	return longest
+}
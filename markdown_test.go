@@ -0,0 +1,88 @@
+package gotfy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownInline(t *testing.T) {
+	r := require.New(t)
+	link := mustParseURL(r, "https://example.com/a_b?q=<x>")
+
+	testCases := []struct {
+		name     string
+		arg      MarkdownInline
+		expected string
+	}{
+		{"plain text", MarkdownText("Deployed v1.2"), `Deployed v1.2`},
+		{"emphasis", MarkdownText("*not bold* or _italic_"), `\*not bold\* or \_italic\_`},
+		{"link", MarkdownText("[click](https://evil.example)"), `\[click\](https://evil.example)`},
+		{"html", MarkdownText("<img src=x> & &amp;"), `\<img src=x\> \& \&amp;`},
+		{"code and backslash", MarkdownText("`rm -rf` \\"), "\\`rm -rf\\` \\\\"},
+		{"heading", MarkdownText("# Hi\n## there"), `\# Hi` + "\n" + `\#\# there`},
+		{"bullets", MarkdownText("- a\n  + b"), `\- a` + "\n" + `  \+ b`},
+		{"setext underline", MarkdownText("title\n==="), "title\n\\==="},
+		{"ordered", MarkdownText("1. one\n2) two\nversion 3.1"), `1\. one` + "\n" + `2\) two` + "\nversion 3.1"},
+		{"table pipe", MarkdownText("a | b"), `a \| b`},
+		{"bold", MarkdownBold(MarkdownText("a*b")), `**a\*b**`},
+		{"bold keeps whitespace outside", MarkdownBold(MarkdownText(" a ")), ` **a** `},
+		{"bold empty", MarkdownBold(MarkdownText("")), ``},
+		{"bold italic", MarkdownItalic(MarkdownBold(MarkdownText("x"))), `***x***`},
+		{"code", MarkdownCode("go test ./..."), "`go test ./...`"},
+		{"code with backticks", MarkdownCode("a `b` c"), "``a `b` c``"},
+		{"code starting with backtick", MarkdownCode("`x"), "`` `x ``"},
+		{"code padded with spaces", MarkdownCode(" x "), "`  x  `"},
+		{"code newlines", MarkdownCode("a\n# b"), "`a # b`"},
+		{"code empty", MarkdownCode(""), ""},
+		{"link", MarkdownLink(link, MarkdownText("the [docs]")), `[the \[docs\]](<https://example.com/a_b?q=\<x\>>)`},
+		{"link without text", MarkdownLink(mustParseURL(r, "https://example.com/a_b")), `[https://example.com/a\_b](<https://example.com/a_b>)`},
+		{"link without URL", MarkdownLink(nil, MarkdownText("docs")), `docs`},
+		{"join", MarkdownJoin(MarkdownText("a "), MarkdownCode("b"), MarkdownInline(" **c**")), "a `b` **c**"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, string(tc.arg), tc.name)
+	}
+}
+
+func TestMarkdownBuilder(t *testing.T) {
+	r := require.New(t)
+
+	var md MarkdownBuilder
+	md.Heading(2, MarkdownText("Deployed api\n#42")).
+		Paragraph(
+			MarkdownText("Commit "), MarkdownCode("abc123"),
+			MarkdownText(" by "), MarkdownBold(MarkdownText("jane_doe")),
+			MarkdownText("\n    indented"),
+		).
+		Paragraph().
+		BulletList(MarkdownText("first"), MarkdownText("second\nline")).
+		BulletList(MarkdownLink(mustParseURL(r, "https://example.com"), MarkdownText("logs"))).
+		NumberedList(MarkdownText("build"), MarkdownText("test")).
+		NumberedList(MarkdownText("deploy")).
+		CodeBlock("go run", "fmt.Println(\"```\")\n").
+		Table(
+			[]MarkdownInline{MarkdownText("Service"), MarkdownText("Status")},
+			[]MarkdownInline{MarkdownText("api|v2"), MarkdownCode("a|b"), MarkdownText("extra")},
+			[]MarkdownInline{MarkdownText("web\nfrontend")},
+		).
+		Heading(9, MarkdownText("Done"))
+
+	r.Equal("## Deployed api \\#42\n\n"+
+		"Commit `abc123` by **jane\\_doe**\nindented\n\n"+
+		"- first\n- second\n  line\n\n"+
+		"* [logs](<https://example.com>)\n\n"+
+		"1. build\n2. test\n\n"+
+		"1) deploy\n\n"+
+		"````go\nfmt.Println(\"```\")\n````\n\n"+
+		"| Service | Status |\n| --- | --- |\n| api\\|v2 | `a\\|b` |\n| web frontend |  |\n\n"+
+		"###### Done", md.String())
+}
+
+func TestMarkdownBuilder_Empty(t *testing.T) {
+	var md MarkdownBuilder
+	md.Paragraph(MarkdownText("  ")).BulletList().Table(nil)
+	require.Equal(t, "", md.String())
+}
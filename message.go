@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +18,7 @@ type Message struct {
 	Call  string `json:"call,omitempty"`  // Phone number for voice call. See: https://docs.ntfy.sh/publish/#phone-calls
 
 	Message  string         `json:"message,omitempty"`  // Message body.
+	Markdown bool           `json:"markdown,omitempty"` // Render the message body as Markdown; see MarkdownBuilder. See: https://docs.ntfy.sh/publish/#markdown-formatting
 	Title    string         `json:"title,omitempty"`    // Message title. See: https://docs.ntfy.sh/publish/#message-title
 	Tags     []string       `json:"tags,omitempty"`     // List of tags that may or not map to emojis. See: https://docs.ntfy.sh/publish/#tags-emojis
 	Priority Priority       `json:"priority,omitempty"` // Message priority with 1=min, 3=default and 5=max. See: https://docs.ntfy.sh/publish/#message-priority
@@ -22,13 +26,20 @@ type Message struct {
 	ClickURL *url.URL       `json:"click,omitempty"`    // Website to open when notification is clicked. See: https://docs.ntfy.sh/publish/#click-action
 	IconURL  *url.URL       `json:"icon,omitempty"`     // URL to use as notification icon. See: https://docs.ntfy.sh/publish/#icons
 
-	Delay time.Duration `json:"delay,omitempty"` // Duration by which to delay delivery. See: https://docs.ntfy.sh/publish/#scheduled-delivery
+	// Delivery may be scheduled by setting one of Delay, DeliverAt or Schedule.
+	// See: https://docs.ntfy.sh/publish/#scheduled-delivery
+	Delay     time.Duration `json:"delay,omitempty"` // Duration by which to delay delivery.
+	DeliverAt time.Time     `json:"-"`               // Time at which to deliver the message, sent as a Unix timestamp.
+	Schedule  string        `json:"-"`               // Delivery time in any form ntfy accepts, e.g. "tomorrow, 10am" or "1639194738".
 
 	AttachURL         *url.URL `json:"attachurl,omitempty"` // URL of an attachment. See: https://docs.ntfy.sh/publish/#attach-file-from-a-url
 	AttachURLFilename string   `json:"filename,omitempty"`  // User-facing file name for the attachment pointed to by AttachURL.
 }
 
-func (m *Message) MarshalJSON() ([]byte, error) {
+// MarshalJSON marshals a Message into the JSON format accepted by Ntfy.
+// It has a value receiver so that both Message and *Message values use it.
+// See: https://docs.ntfy.sh/publish/#publish-as-json
+func (m Message) MarshalJSON() ([]byte, error) {
 	buf, err := json.Marshal(m.Topic)
 	if err != nil {
 		return nil, err
@@ -43,6 +54,10 @@ func (m *Message) MarshalJSON() ([]byte, error) {
 		buf = append(buf, fmt.Sprintf(`,"message":%s`, mm)...)
 	}
 
+	if m.Markdown {
+		buf = append(buf, `,"markdown":true`...)
+	}
+
 	if x := m.Title; x != "" {
 		mm, err := json.Marshal(x)
 		if err != nil {
@@ -97,8 +112,12 @@ func (m *Message) MarshalJSON() ([]byte, error) {
 		buf = append(buf, fmt.Sprintf(`,"%s":%s`, v.name, mm)...)
 	}
 
-	if x := m.Delay; x > 0 {
-		mm, err := json.Marshal(x.String())
+	delay, err := m.delayValue()
+	if err != nil {
+		return nil, err
+	}
+	if delay != "" {
+		mm, err := json.Marshal(delay)
 		if err != nil {
 			return nil, err
 		}
@@ -144,3 +163,112 @@ func urlString(u *url.URL) ([]byte, error) {
 
 	return json.Marshal(s)
 }
+
+// UnmarshalJSON unmarshals a Message from JSON. It accepts everything MarshalJSON emits,
+// so that messages can be loaded from config files, queues or fixtures.
+func (m *Message) UnmarshalJSON(b []byte) error {
+	var aux struct {
+		Topic             string        `json:"topic"`
+		Email             string        `json:"email"`
+		Call              string        `json:"call"`
+		Message           string        `json:"message"`
+		Markdown          bool          `json:"markdown"`
+		Title             string        `json:"title"`
+		Tags              []string      `json:"tags"`
+		Priority          Priority      `json:"priority"`
+		Actions           ActionButtons `json:"actions"`
+		ClickURL          string        `json:"click"`
+		IconURL           string        `json:"icon"`
+		Delay             string        `json:"delay"`
+		AttachURL         string        `json:"attachurl"`
+		AttachURLFilename string        `json:"filename"`
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	msg := Message{
+		Topic:             aux.Topic,
+		Email:             aux.Email,
+		Call:              aux.Call,
+		Message:           aux.Message,
+		Markdown:          aux.Markdown,
+		Title:             aux.Title,
+		Tags:              aux.Tags,
+		Priority:          aux.Priority,
+		Actions:           aux.Actions,
+		AttachURLFilename: aux.AttachURLFilename,
+	}
+
+	for _, v := range []struct {
+		name string
+		raw  string
+		dst  **url.URL
+	}{
+		{"click", aux.ClickURL, &msg.ClickURL},
+		{"icon", aux.IconURL, &msg.IconURL},
+		{"attachurl", aux.AttachURL, &msg.AttachURL},
+	} {
+		if v.raw == "" {
+			continue
+		}
+		u, err := url.Parse(v.raw)
+		if err != nil {
+			return fmt.Errorf("invalid %s URL: %w", v.name, err)
+		}
+		*v.dst = u
+	}
+
+	// the delay is a duration, a Unix timestamp or an expression left for the server to interpret
+	if aux.Delay != "" {
+		if d, err := parseDelay(aux.Delay); err == nil {
+			msg.Delay = d
+		} else if s := strings.TrimSpace(aux.Delay); unixTimestamp.MatchString(s) {
+			ts, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid delay %q: %w", s, err)
+			}
+			msg.DeliverAt = time.Unix(ts, 0)
+		} else {
+			msg.Schedule = aux.Delay
+		}
+	}
+
+	*m = msg
+	return nil
+}
+
+// delayPattern matches the durations ntfy accepts for delayed delivery, e.g. "30m", "2h" or "3 days".
+var delayPattern = regexp.MustCompile(`^(\d+)\s*(d|days?|h|hours?|m|mins?|minutes?|s|secs?|seconds?)$`)
+
+// parseDelay parses a delay in one of the duration formats ntfy accepts, or in the format
+// produced by time.Duration.String.
+// See: https://docs.ntfy.sh/publish/#scheduled-delivery
+func parseDelay(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+
+	match := delayPattern.FindStringSubmatch(strings.ToLower(s))
+	if match == nil {
+		return 0, fmt.Errorf("invalid delay %q", s)
+	}
+
+	n, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid delay %q: %w", s, err)
+	}
+
+	unit := time.Second
+	switch match[2][0] {
+	case 'd':
+		unit = 24 * time.Hour
+	case 'h':
+		unit = time.Hour
+	case 'm':
+		unit = time.Minute
+	}
+
+	return time.Duration(n) * unit, nil
+}
@@ -3,10 +3,13 @@ package gotfy
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMessageMarshalJSON(mainTest *testing.T) {
@@ -30,6 +33,11 @@ func TestMessageMarshalJSON(mainTest *testing.T) {
 			arg:      Message{Message: "Message"},
 			expected: `{"topic":"","message":"Message"}`,
 		},
+		{
+			name:     "Markdown",
+			arg:      Message{Message: "**Message**", Markdown: true},
+			expected: `{"topic":"","message":"**Message**","markdown":true}`,
+		},
 		{
 			name:     "Title",
 			arg:      Message{Title: "Title"},
@@ -147,3 +155,215 @@ func TestMessageMarshalJSON(mainTest *testing.T) {
 		}
 	}
 }
+
+func TestMessageUnmarshalJSON(t *testing.T) {
+	r := require.New(t)
+
+	var actual Message
+	r.NoError(json.Unmarshal([]byte(`{
+		"topic": "mytopic",
+		"message": "Disk space is **low**",
+		"markdown": true,
+		"title": "Low disk space alert",
+		"tags": ["warning"],
+		"priority": 4,
+		"actions": [{"action":"view","label":"Admin panel","url":"https://filesrv.lan/admin"}],
+		"click": "https://homecam.mynet.lan/incident/1234",
+		"icon": "https://example.com/icon.png",
+		"attachurl": "https://example.com/file.jpg",
+		"filename": "file.jpg",
+		"delay": "3 days",
+		"email": "phil@example.com",
+		"call": "+12065551234"
+	}`), &actual))
+
+	r.Equal(Message{
+		Topic:    "mytopic",
+		Message:  "Disk space is **low**",
+		Markdown: true,
+		Title:    "Low disk space alert",
+		Tags:     []string{Warning},
+		Priority: PriorityHigh,
+		Actions: []ActionButton{&ViewAction{
+			Label: "Admin panel",
+			Link:  mustParseURL(r, "https://filesrv.lan/admin"),
+		}},
+		ClickURL:          mustParseURL(r, "https://homecam.mynet.lan/incident/1234"),
+		IconURL:           mustParseURL(r, "https://example.com/icon.png"),
+		AttachURL:         mustParseURL(r, "https://example.com/file.jpg"),
+		AttachURLFilename: "file.jpg",
+		Delay:             72 * time.Hour,
+		Email:             "phil@example.com",
+		Call:              "+12065551234",
+	}, actual)
+}
+
+func TestMessageUnmarshalJSON_Delay(t *testing.T) {
+	testCases := []struct {
+		arg      string
+		expected time.Duration
+	}{
+		{"30m", 30 * time.Minute},
+		{"1h30m0s", 90 * time.Minute},
+		{"2h", 2 * time.Hour},
+		{"1d", 24 * time.Hour},
+		{"10 seconds", 10 * time.Second},
+		{"5 mins", 5 * time.Minute},
+		{"1 hour", time.Hour},
+	}
+
+	r := require.New(t)
+	for _, tc := range testCases {
+		var actual Message
+		r.NoError(json.Unmarshal([]byte(fmt.Sprintf(`{"topic":"t","delay":%q}`, tc.arg)), &actual), tc.arg)
+		r.Equal(tc.expected, actual.Delay, tc.arg)
+	}
+
+	var actual Message
+	r.NoError(json.Unmarshal([]byte(`{"topic":"t","delay":"1639194738"}`), &actual))
+	r.Equal(Message{Topic: "t", DeliverAt: time.Unix(1639194738, 0)}, actual)
+
+	// anything else is left for the server to interpret
+	actual = Message{}
+	r.NoError(json.Unmarshal([]byte(`{"topic":"t","delay":"tomorrow, 10am"}`), &actual))
+	r.Equal(Message{Topic: "t", Schedule: "tomorrow, 10am"}, actual)
+}
+
+func TestMessageMarshalJSON_Schedule(t *testing.T) {
+	r := require.New(t)
+
+	buf, err := json.Marshal(Message{Topic: "t", DeliverAt: time.Unix(1639194738, 0)})
+	r.NoError(err)
+	r.Equal(`{"topic":"t","delay":"1639194738"}`, string(buf))
+
+	buf, err = json.Marshal(Message{Topic: "t", Schedule: " tomorrow, 10am "})
+	r.NoError(err)
+	r.Equal(`{"topic":"t","delay":"tomorrow, 10am"}`, string(buf))
+
+	_, err = json.Marshal(Message{Topic: "t", Delay: time.Hour, Schedule: "tomorrow, 10am"})
+	r.ErrorIs(err, ErrScheduleConflict)
+	_, err = json.Marshal(Message{Topic: "t", Delay: time.Hour, DeliverAt: time.Now()})
+	r.ErrorIs(err, ErrScheduleConflict)
+}
+
+func TestMessageJSONRoundTrip(t *testing.T) {
+	r := require.New(t)
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+		original := randomMessage(r, rnd)
+
+		buf, err := json.Marshal(&original)
+		r.NoError(err)
+
+		var decoded Message
+		r.NoError(json.Unmarshal(buf, &decoded), string(buf))
+		r.Equal(original, decoded, string(buf))
+	}
+}
+
+func mustParseURL(r *require.Assertions, s string) *url.URL {
+	u, err := url.Parse(s)
+	r.NoError(err)
+	return u
+}
+
+// randomMessage returns a Message with a random subset of its fields set.
+func randomMessage(r *require.Assertions, rnd *rand.Rand) Message {
+	str := func() string {
+		const chars = "abcdefghijklmnopqrstuvwxyz ABC0123456789\"\\\n\t{}<>&😀é"
+		runes := []rune(chars)
+		out := make([]rune, 1+rnd.Intn(20))
+		for i := range out {
+			out[i] = runes[rnd.Intn(len(runes))]
+		}
+		return string(out)
+	}
+	maybeStr := func() string {
+		if rnd.Intn(2) == 0 {
+			return ""
+		}
+		return str()
+	}
+	maybeURL := func() *url.URL {
+		if rnd.Intn(2) == 0 {
+			return nil
+		}
+		return mustParseURL(r, fmt.Sprintf("https://example%d.com/path/%d?q=%d#frag", rnd.Intn(10), rnd.Intn(100), rnd.Intn(100)))
+	}
+	maybeMap := func() map[string]string {
+		if rnd.Intn(2) == 0 {
+			return nil
+		}
+		m := make(map[string]string)
+		for i := 0; i < 1+rnd.Intn(3); i++ {
+			m[str()] = str()
+		}
+		return m
+	}
+
+	m := Message{
+		Topic:             str(),
+		Email:             maybeStr(),
+		Call:              maybeStr(),
+		Message:           maybeStr(),
+		Markdown:          rnd.Intn(2) == 0,
+		Title:             maybeStr(),
+		Priority:          Priority(rnd.Intn(6)),
+		ClickURL:          maybeURL(),
+		IconURL:           maybeURL(),
+		AttachURL:         maybeURL(),
+		AttachURLFilename: maybeStr(),
+	}
+
+	switch rnd.Intn(4) {
+	case 0:
+		m.Delay = time.Duration(1+rnd.Int63n(int64(72*time.Hour))) * time.Duration(rnd.Intn(2)*999+1)
+	case 1:
+		m.DeliverAt = time.Unix(1700000000+rnd.Int63n(1e8), 0)
+	case 2:
+		m.Schedule = fmt.Sprintf("tomorrow, %dam", 1+rnd.Intn(12))
+	}
+
+	for i := rnd.Intn(3); i > 0; i-- {
+		m.Tags = append(m.Tags, str())
+	}
+
+	for i := rnd.Intn(4); i > 0; i-- {
+		switch rnd.Intn(3) {
+		case 0:
+			m.Actions = append(m.Actions, &ViewAction{Label: str(), Link: maybeURL(), Clear: rnd.Intn(2) == 0})
+		case 1:
+			m.Actions = append(m.Actions, &HttpAction[string]{
+				Label:   str(),
+				URL:     maybeURL(),
+				Method:  maybeStr(),
+				Headers: maybeMap(),
+				Body:    maybeStr(),
+				Clear:   rnd.Intn(2) == 0,
+			})
+		case 2:
+			m.Actions = append(m.Actions, &BroadcastAction{
+				Label:  str(),
+				Intent: maybeStr(),
+				Extras: maybeMap(),
+				Clear:  rnd.Intn(2) == 0,
+			})
+		}
+	}
+
+	return m
+}
+
+func TestMessageMarshalJSON_ValueAndPointer(t *testing.T) {
+	r := require.New(t)
+
+	m := Message{Topic: "topic", Delay: time.Minute, ClickURL: &url.URL{Scheme: "h", Host: "t.com"}}
+	byValue, err := json.Marshal(m)
+	r.NoError(err)
+	byPointer, err := json.Marshal(&m)
+	r.NoError(err)
+
+	r.Equal(`{"topic":"topic","click":"h://t.com","delay":"1m0s"}`, string(byValue))
+	r.Equal(byValue, byPointer)
+}
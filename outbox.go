@@ -0,0 +1,368 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Outbox is a Publisher which stores messages on disk before sending them, so that messages
+// published while the server is unreachable, or shortly before a crash, are delivered later.
+//
+// Messages are delivered one at a time, in the order they were sent, and are only removed from
+// the outbox once the server has accepted them. A crash between the server accepting a message and
+// the outbox recording it causes the message to be delivered again after a restart.
+type Outbox interface {
+	// Send stores m in the outbox and then delivers it, along with any messages stored before it.
+	// If m cannot be delivered yet, Send returns an error matching ErrDeferred: the message is kept,
+	// and will be delivered in the background or by a later call to Send or Flush.
+	// Messages whose scheduled delivery is already too soon are rejected without being stored.
+	Send(ctx context.Context, m Message) (*SendResponse, error)
+
+	// Flush delivers all stored messages, stopping at the first one that fails.
+	// Messages whose scheduled delivery time has become too soon while they were stored are
+	// removed without being delivered; once the others are delivered, Flush returns the error
+	// for the first of them.
+	Flush(ctx context.Context) error
+
+	// Len returns the number of messages waiting to be delivered.
+	Len() int
+
+	// Close stops background delivery and closes the underlying files.
+	// Messages which have not been delivered are kept for the next time the outbox is opened.
+	Close() error
+}
+
+// Defaults used when the corresponding OutboxOpts field is zero.
+const (
+	DefaultOutboxSegmentSize = 4 << 20
+	DefaultOutboxMaxSize     = 64 << 20
+)
+
+var (
+	// ErrDeferred is matched by the error returned from Outbox.Send when a message was stored
+	// but could not be delivered yet.
+	ErrDeferred = errors.New("message stored for later delivery")
+
+	// ErrOutboxFull is returned when storing a message would exceed OutboxOpts.MaxSize.
+	ErrOutboxFull = errors.New("outbox is full")
+)
+
+// DeferredError is returned by Outbox.Send when a message was stored but could not be delivered yet.
+// It matches ErrDeferred, and unwraps to the reason delivery failed.
+type DeferredError struct {
+	Err error
+}
+
+func (e *DeferredError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrDeferred, e.Err)
+}
+
+func (e *DeferredError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DeferredError) Is(target error) bool {
+	return target == ErrDeferred
+}
+
+// OutboxOpts contains the configuration options for a new Outbox.
+type OutboxOpts struct {
+	// Publisher delivers the stored messages.
+	Publisher Publisher
+
+	// Dir is the directory holding the outbox's files. It is created if needed, and must not be
+	// shared with any other outbox, including one in another process.
+	Dir string
+
+	// SyncInterval controls how often writes are flushed to disk. If zero, every write is synced
+	// before Send returns, so that no stored message is lost even if the machine loses power.
+	// If positive, writes are synced in the background at most this long after they happen.
+	// If negative, syncing is left to the operating system, which survives a process crash
+	// but not a power loss.
+	SyncInterval time.Duration
+
+	// SegmentSize is the size, in bytes, at which the log moves on to a new file.
+	// Files are deleted once all of their messages have been delivered.
+	// If zero, DefaultOutboxSegmentSize is used.
+	SegmentSize int64
+
+	// MaxSize bounds the total size of the log, in bytes; Send returns ErrOutboxFull once it is
+	// reached. If zero, DefaultOutboxMaxSize is used; if negative, the size is unbounded.
+	MaxSize int64
+
+	// Backoff controls the delay between background delivery attempts while the server is
+	// unreachable. If nil, DefaultBackoff is used.
+	Backoff *Backoff
+
+	// DisableAutoFlush stops the outbox from delivering messages in the background;
+	// they are then only delivered by Send and Flush.
+	DisableAutoFlush bool
+
+	// Discard, if set, is called when delivering a message fails, and reports whether the message
+	// should be removed without being delivered, e.g. because the server rejected it permanently.
+	// By default, a message is kept until it is delivered, and holds up every message after it.
+	// Messages whose scheduled delivery time has become too soon are always removed; Discard is
+	// still called for them, so that they can be reported.
+	Discard func(m Message, err error) bool
+}
+
+type outboxEntry struct {
+	walEntry
+
+	// the outcome, set once the entry has left the outbox
+	resp *SendResponse
+	err  error
+}
+
+type outbox struct {
+	publisher Publisher
+	backoff   Backoff
+	discard   func(Message, error) bool
+	now       func() time.Time
+
+	mu      sync.Mutex
+	wal     *wal
+	pending []*outboxEntry
+	closed  bool
+
+	// flushing serializes deliveries, so that messages are sent in order.
+	flushing chan struct{}
+
+	wake   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	bg     sync.WaitGroup
+}
+
+// NewOutbox opens the outbox in opts.Dir, recovering any messages stored by a previous process,
+// and starts delivering them in the background.
+func NewOutbox(opts OutboxOpts) (Outbox, error) {
+	if opts.Publisher == nil {
+		return nil, errors.New("publisher must not be nil")
+	}
+	if opts.Dir == "" {
+		return nil, errors.New("outbox directory must not be empty")
+	}
+
+	walOpts := walOpts{
+		syncWrites:  opts.SyncInterval == 0,
+		segmentSize: opts.SegmentSize,
+		maxSize:     opts.MaxSize,
+	}
+	if walOpts.segmentSize <= 0 {
+		walOpts.segmentSize = DefaultOutboxSegmentSize
+	}
+	if walOpts.maxSize == 0 {
+		walOpts.maxSize = DefaultOutboxMaxSize
+	}
+
+	w, entries, err := openWAL(opts.Dir, walOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	retv := &outbox{
+		publisher: opts.Publisher,
+		backoff:   DefaultBackoff,
+		discard:   opts.Discard,
+		now:       time.Now,
+		wal:       w,
+		flushing:  make(chan struct{}, 1),
+		wake:      make(chan struct{}, 1),
+	}
+	if opts.Backoff != nil {
+		retv.backoff = *opts.Backoff
+	}
+	for _, e := range entries {
+		retv.pending = append(retv.pending, &outboxEntry{walEntry: e})
+	}
+	retv.ctx, retv.cancel = context.WithCancel(context.Background())
+
+	if opts.SyncInterval > 0 {
+		retv.bg.Add(1)
+		go retv.syncLoop(opts.SyncInterval)
+	}
+	if !opts.DisableAutoFlush {
+		retv.bg.Add(1)
+		go retv.flushLoop()
+		if len(retv.pending) > 0 {
+			retv.notify()
+		}
+	}
+
+	return retv, nil
+}
+
+func (o *outbox) Send(ctx context.Context, m Message) (*SendResponse, error) {
+	// the publisher checks the maximum delay once the message is delivered
+	if err := m.checkSchedule(o.now(), 0); err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+	// a delay counts from when the message is sent, not from when the outbox delivers it
+	if m.Delay > 0 {
+		m.DeliverAt = o.now().Add(m.Delay)
+		m.Delay = 0
+	}
+
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil, ErrPublisherClosed
+	}
+	id, err := o.wal.append(m)
+	if err != nil {
+		o.mu.Unlock()
+		return nil, err
+	}
+	e := &outboxEntry{walEntry: walEntry{id: id, m: m}}
+	o.pending = append(o.pending, e)
+	o.mu.Unlock()
+
+	if _, err := o.flush(ctx, id); err != nil {
+		o.notify()
+		return nil, &DeferredError{Err: err}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return e.resp, e.err
+}
+
+func (o *outbox) Flush(ctx context.Context) error {
+	dropped, err := o.flush(ctx, 0)
+	if err != nil {
+		return err
+	}
+	return dropped
+}
+
+func (o *outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+func (o *outbox) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	o.mu.Unlock()
+
+	o.cancel()
+	o.bg.Wait()
+
+	// wait for a delivery started by Send or Flush to be recorded
+	o.flushing <- struct{}{}
+	defer func() { <-o.flushing }()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.wal.close()
+}
+
+// flush delivers stored messages in order, until the message with the given ID has left the
+// outbox, or until none are left if until is zero. Messages whose scheduled delivery can no longer
+// be met are removed, and the error for the first of them is returned as dropped.
+func (o *outbox) flush(ctx context.Context, until uint64) (dropped, err error) {
+	select {
+	case o.flushing <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-o.flushing }()
+
+	for {
+		o.mu.Lock()
+		if o.closed {
+			o.mu.Unlock()
+			return dropped, ErrPublisherClosed
+		}
+		if len(o.pending) == 0 || (until != 0 && o.pending[0].id > until) {
+			o.mu.Unlock()
+			return dropped, nil
+		}
+		e := o.pending[0]
+		o.mu.Unlock()
+
+		resp, err := o.publisher.Send(ctx, e.m)
+		switch {
+		case err == nil:
+		case isScheduleError(err):
+			// retrying won't help, and the message would hold up every message after it
+			if o.discard != nil {
+				o.discard(e.m, err)
+			}
+			if dropped == nil {
+				dropped = fmt.Errorf("message dropped from the outbox: %w", err)
+			}
+		case o.discard == nil || !o.discard(e.m, err):
+			return dropped, err
+		}
+
+		o.mu.Lock()
+		ackErr := o.wal.ack(e.id)
+		o.pending[0] = nil
+		o.pending = o.pending[1:]
+		e.resp, e.err = resp, err
+		o.mu.Unlock()
+
+		if ackErr != nil {
+			// the message was delivered, but will be delivered again after a restart
+			return dropped, ackErr
+		}
+	}
+}
+
+// notify wakes the background delivery loop.
+func (o *outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (o *outbox) flushLoop() {
+	defer o.bg.Done()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-o.wake:
+		}
+
+		// messages dropped here are only reported through OutboxOpts.Discard
+		for attempt := 0; ; attempt++ {
+			if _, err := o.flush(o.ctx, 0); err == nil {
+				break
+			}
+			if sleepContext(o.ctx, o.backoff.Delay(attempt)) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (o *outbox) syncLoop(interval time.Duration) {
+	defer o.bg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-ticker.C:
+			o.mu.Lock()
+			_ = o.wal.sync()
+			o.mu.Unlock()
+		}
+	}
+}
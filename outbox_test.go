@@ -0,0 +1,309 @@
+package gotfy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher delivers messages while online, and remembers them in order.
+type recordingPublisher struct {
+	online atomic.Bool
+
+	mu        sync.Mutex
+	delivered []string
+}
+
+func (p *recordingPublisher) Send(ctx context.Context, m Message) (*SendResponse, error) {
+	if !p.online.Load() {
+		return nil, errors.New("network is unreachable")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.delivered = append(p.delivered, m.Message)
+	return &SendResponse{MessageEvent: MessageEvent{ID: m.Message, Topic: m.Topic}}, nil
+}
+
+func (p *recordingPublisher) get() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.delivered...)
+}
+
+func newTestOutbox(t *testing.T, dir string, pub Publisher, opts OutboxOpts) Outbox {
+	opts.Dir = dir
+	opts.Publisher = pub
+	opts.DisableAutoFlush = true
+	sut, err := NewOutbox(opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sut.Close() })
+	return sut
+}
+
+func Test_Outbox_DefersAndDeliversInOrder(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+
+	pub := &recordingPublisher{}
+	sut := newTestOutbox(t, dir, pub, OutboxOpts{})
+
+	for _, msg := range []string{"1", "2", "3"} {
+		_, err := sut.Send(context.Background(), Message{Topic: "mytopic", Message: msg})
+		r.ErrorIs(err, ErrDeferred)
+		r.EqualError(err, "message stored for later delivery: network is unreachable")
+	}
+	r.Equal(3, sut.Len())
+
+	// Send delivers everything stored before the message, too
+	pub.online.Store(true)
+	resp, err := sut.Send(context.Background(), Message{Topic: "mytopic", Message: "4"})
+	r.NoError(err)
+	r.Equal("4", resp.ID)
+	r.Equal([]string{"1", "2", "3", "4"}, pub.get())
+	r.Equal(0, sut.Len())
+	r.NoError(sut.Close())
+
+	// nothing is delivered twice
+	sut = newTestOutbox(t, dir, pub, OutboxOpts{})
+	r.Equal(0, sut.Len())
+}
+
+func Test_Outbox_KeepsMessagesAcrossRestarts(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+
+	pub := &recordingPublisher{}
+	sut := newTestOutbox(t, dir, pub, OutboxOpts{SyncInterval: -1})
+	for _, msg := range []string{"1", "2", "3"} {
+		_, err := sut.Send(context.Background(), Message{Topic: "mytopic", Message: msg, Tags: []string{"tag"}})
+		r.ErrorIs(err, ErrDeferred)
+	}
+	r.NoError(sut.Close())
+
+	_, err := sut.Send(context.Background(), Message{Topic: "mytopic"})
+	r.ErrorIs(err, ErrPublisherClosed)
+
+	pub.online.Store(true)
+	sut, err = NewOutbox(OutboxOpts{Dir: dir, Publisher: pub})
+	r.NoError(err)
+	defer sut.Close()
+
+	r.Eventually(func() bool { return sut.Len() == 0 }, time.Second, time.Millisecond)
+	r.Equal([]string{"1", "2", "3"}, pub.get())
+}
+
+func Test_Outbox_RotatesAndCapsSize(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+
+	pub := &recordingPublisher{}
+	sut := newTestOutbox(t, dir, pub, OutboxOpts{SegmentSize: 200, MaxSize: 1000})
+
+	msg := Message{Topic: "mytopic", Message: strings.Repeat("x", 50)}
+	var stored int
+	for {
+		_, err := sut.Send(context.Background(), msg)
+		if errors.Is(err, ErrOutboxFull) {
+			break
+		}
+		r.ErrorIs(err, ErrDeferred)
+		stored++
+	}
+	r.Equal(stored, sut.Len())
+	r.Greater(stored, 5)
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	r.NoError(err)
+	r.Greater(len(segments), 2)
+
+	var size int64
+	for _, name := range segments {
+		fi, err := os.Stat(name)
+		r.NoError(err)
+		size += fi.Size()
+	}
+	r.LessOrEqual(size, int64(1000))
+
+	pub.online.Store(true)
+	r.NoError(sut.Flush(context.Background()))
+	r.Len(pub.get(), stored)
+
+	// delivered segments are removed, making room for new messages
+	segments, err = filepath.Glob(filepath.Join(dir, "*.wal"))
+	r.NoError(err)
+	r.Len(segments, 1)
+	for i := 0; i < stored; i++ {
+		_, err := sut.Send(context.Background(), msg)
+		r.NoError(err)
+	}
+}
+
+func Test_Outbox_TruncatesTornRecord(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+
+	pub := &recordingPublisher{}
+	sut := newTestOutbox(t, dir, pub, OutboxOpts{})
+	for _, msg := range []string{"1", "2"} {
+		_, err := sut.Send(context.Background(), Message{Topic: "mytopic", Message: msg})
+		r.ErrorIs(err, ErrDeferred)
+	}
+	r.NoError(sut.Close())
+
+	// simulate a crash in the middle of appending a third message
+	segments, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	r.NoError(err)
+	r.Len(segments, 1)
+	rec := encodeWALRecord(walRecordAppend, 3, []byte(`{"topic":"mytopic","message":"3"}`))
+	f, err := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0)
+	r.NoError(err)
+	_, err = f.Write(rec[:len(rec)-5])
+	r.NoError(err)
+	r.NoError(f.Close())
+
+	pub.online.Store(true)
+	sut = newTestOutbox(t, dir, pub, OutboxOpts{})
+	r.Equal(2, sut.Len())
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic", Message: "4"})
+	r.NoError(err)
+	r.Equal([]string{"1", "2", "4"}, pub.get())
+}
+
+func Test_Outbox_Discard(t *testing.T) {
+	r := require.New(t)
+
+	pub := &recordingPublisher{}
+	rejected := &APIError{HTTPCode: 400, Code: 40001, Message: "invalid request"}
+	sut := newTestOutbox(t, t.TempDir(), publisherFunc(func(ctx context.Context, m Message) (*SendResponse, error) {
+		if m.Message == "bad" {
+			return nil, rejected
+		}
+		return pub.Send(ctx, m)
+	}), OutboxOpts{Discard: func(m Message, err error) bool { return isPermanent(err) }})
+	pub.online.Store(true)
+
+	_, err := sut.Send(context.Background(), Message{Topic: "mytopic", Message: "bad"})
+	r.ErrorIs(err, rejected)
+	r.NotErrorIs(err, ErrDeferred)
+
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic", Message: "good"})
+	r.NoError(err)
+	r.Equal([]string{"good"}, pub.get())
+	r.Equal(0, sut.Len())
+}
+
+func Test_Outbox_DropsStaleSchedules(t *testing.T) {
+	r := require.New(t)
+
+	// the publisher checks schedules as a real one would, at a time the test controls
+	now := time.Unix(1700000000, 0)
+	pub := &recordingPublisher{}
+	sut := newTestOutbox(t, t.TempDir(), publisherFunc(func(ctx context.Context, m Message) (*SendResponse, error) {
+		if err := m.checkSchedule(now, DefaultMaxDelay); err != nil {
+			return nil, err
+		}
+		return pub.Send(ctx, m)
+	}), OutboxOpts{})
+	sut.(*outbox).now = func() time.Time { return now }
+
+	_, err := sut.Send(context.Background(), Message{Topic: "mytopic", Message: "soon", Delay: time.Second})
+	r.ErrorIs(err, ErrDelayTooShort)
+	r.NotErrorIs(err, ErrDeferred)
+	r.Equal(0, sut.Len(), "a message scheduled too soon should not be stored")
+
+	// the server is down until after the first message was due
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic", Message: "stale", DeliverAt: now.Add(time.Minute)})
+	r.ErrorIs(err, ErrDeferred)
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic", Message: "delayed", Delay: time.Minute})
+	r.ErrorIs(err, ErrDeferred)
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic", Message: "later"})
+	r.ErrorIs(err, ErrDeferred)
+	r.Equal(3, sut.Len())
+
+	now = now.Add(time.Hour)
+	pub.online.Store(true)
+	err = sut.Flush(context.Background())
+	r.ErrorIs(err, ErrDelayTooShort)
+	r.EqualError(err, "message dropped from the outbox: delivery is scheduled too soon: delivery time is in the past")
+	r.Equal([]string{"later"}, pub.get())
+	r.Equal(0, sut.Len())
+	r.NoError(sut.Flush(context.Background()))
+}
+
+// Test_Outbox_RecoversAfterCrash runs itself in a child process, which stores messages while
+// offline and is then killed while delivering them.
+func Test_Outbox_RecoversAfterCrash(t *testing.T) {
+	const crashAt = "3"
+
+	if dir := os.Getenv("GOTFY_OUTBOX_CRASH_DIR"); dir != "" {
+		log, err := os.OpenFile(filepath.Join(dir, "delivered"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			os.Exit(2)
+		}
+
+		var online atomic.Bool
+		sut, err := NewOutbox(OutboxOpts{
+			Dir: filepath.Join(dir, "outbox"),
+			Publisher: publisherFunc(func(ctx context.Context, m Message) (*SendResponse, error) {
+				if !online.Load() {
+					return nil, errors.New("network is unreachable")
+				}
+				if m.Message == crashAt {
+					os.Exit(3) // killed while the request is in flight
+				}
+				_, _ = log.WriteString(m.Message + "\n")
+				_ = log.Sync()
+				return &SendResponse{}, nil
+			}),
+			DisableAutoFlush: true,
+		})
+		if err != nil {
+			os.Exit(2)
+		}
+		for _, msg := range []string{"1", "2", "3", "4", "5"} {
+			if _, err := sut.Send(context.Background(), Message{Topic: "mytopic", Message: msg}); !errors.Is(err, ErrDeferred) {
+				os.Exit(2)
+			}
+		}
+		online.Store(true)
+		_ = sut.Flush(context.Background())
+		os.Exit(0) // not reached
+	}
+
+	r := require.New(t)
+	dir := t.TempDir()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^Test_Outbox_RecoversAfterCrash$")
+	cmd.Env = append(os.Environ(), "GOTFY_OUTBOX_CRASH_DIR="+dir)
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	r.True(errors.As(err, &exitErr), "unexpected error: %v", err)
+	r.Equal(3, exitErr.ExitCode())
+
+	f, err := os.Open(filepath.Join(dir, "delivered"))
+	r.NoError(err)
+	defer f.Close()
+	var delivered []string
+	for s := bufio.NewScanner(f); s.Scan(); {
+		delivered = append(delivered, s.Text())
+	}
+	r.Equal([]string{"1", "2"}, delivered)
+
+	pub := &recordingPublisher{}
+	pub.online.Store(true)
+	sut := newTestOutbox(t, filepath.Join(dir, "outbox"), pub, OutboxOpts{})
+	r.Equal(3, sut.Len())
+	r.NoError(sut.Flush(context.Background()))
+	r.Equal([]string{"3", "4", "5"}, pub.get())
+}
@@ -0,0 +1,380 @@
+package gotfy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The outbox's write-ahead log is a sequence of segment files, named after their sequence number.
+// Each segment holds records of the form
+//
+//	length (uint32) | CRC-32C of body (uint32) | body
+//
+// where body is a record type, an entry ID (uint64) and, for appends, the message as JSON.
+// Messages are delivered in order, so deletions are recorded as an ack of the latest delivered ID;
+// every entry up to it is deleted. A new segment starts with the current ack, so that it stays
+// readable once older segments have been removed.
+const (
+	walSegmentExt = ".wal"
+
+	walRecordAppend byte = 1
+	walRecordAck    byte = 2
+
+	walHeaderSize    = 8
+	walMaxRecordSize = 16 << 20
+)
+
+var walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errWALTorn is returned for a record which was only partially written, or is otherwise corrupt.
+var errWALTorn = errors.New("torn or corrupt record")
+
+type walSegment struct {
+	seq   uint64
+	size  int64
+	maxID uint64 // highest entry ID appended to the segment, or zero
+}
+
+type walEntry struct {
+	id uint64
+	m  Message
+}
+
+type walOpts struct {
+	syncWrites  bool // sync after every write
+	segmentSize int64
+	maxSize     int64
+}
+
+// wal is the outbox's on-disk log. It is not safe for concurrent use.
+type wal struct {
+	dir  string
+	opts walOpts
+
+	segments []*walSegment // oldest first; the last one is active
+	active   *os.File
+	size     int64 // total size of all segments
+
+	acked  uint64 // ID of the latest delivered entry
+	nextID uint64
+	dirty  bool // written since the last sync
+}
+
+// openWAL opens the log in dir, creating the directory if needed, and returns the entries which
+// have not been acked yet. A torn record at the end of the newest segment, as left behind by a
+// crash, is truncated.
+func openWAL(dir string, opts walOpts) (*wal, []walEntry, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "*"+walSegmentExt))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list outbox segments: %w", err)
+	}
+
+	w := &wal{dir: dir, opts: opts, nextID: 1}
+	for _, name := range names {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), walSegmentExt), 10, 64)
+		if err != nil {
+			continue // not ours
+		}
+		w.segments = append(w.segments, &walSegment{seq: seq})
+	}
+	sort.Slice(w.segments, func(i, j int) bool { return w.segments[i].seq < w.segments[j].seq })
+
+	var entries []walEntry
+	for i, seg := range w.segments {
+		last := i == len(w.segments)-1
+		segEntries, err := w.readSegment(seg, last)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, segEntries...)
+		w.size += seg.size
+	}
+
+	pending := entries[:0]
+	for _, e := range entries {
+		if e.id > w.acked {
+			pending = append(pending, e)
+		}
+	}
+
+	if len(w.segments) == 0 {
+		if err := w.rotate(); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		seg := w.segments[len(w.segments)-1]
+		f, err := os.OpenFile(w.segmentPath(seg.seq), os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open outbox segment: %w", err)
+		}
+		w.active = f
+	}
+
+	if err := w.removeDelivered(); err != nil {
+		_ = w.close()
+		return nil, nil, err
+	}
+	return w, pending, nil
+}
+
+// readSegment replays seg, updating w's IDs, and returns the entries appended to it.
+func (w *wal) readSegment(seg *walSegment, last bool) ([]walEntry, error) {
+	path := w.segmentPath(seg.seq)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox segment: %w", err)
+	}
+	defer f.Close()
+
+	var entries []walEntry
+	r := bufio.NewReader(f)
+	for {
+		typ, id, payload, n, err := readWALRecord(r)
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if errors.Is(err, errWALTorn) && last {
+			// the process stopped while writing; nothing after this record was acknowledged
+			if err := os.Truncate(path, seg.size); err != nil {
+				return nil, fmt.Errorf("failed to truncate outbox segment: %w", err)
+			}
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read outbox segment %s: %w", path, err)
+		}
+		seg.size += int64(n)
+
+		switch typ {
+		case walRecordAppend:
+			var m Message
+			if err := m.UnmarshalJSON(payload); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal outbox message from JSON: %w", err)
+			}
+			entries = append(entries, walEntry{id: id, m: m})
+			seg.maxID = id
+			if id >= w.nextID {
+				w.nextID = id + 1
+			}
+		case walRecordAck:
+			if id > w.acked {
+				w.acked = id
+			}
+		default:
+			return nil, fmt.Errorf("failed to read outbox segment %s: unknown record type %d", path, typ)
+		}
+	}
+}
+
+// append writes m to the log and returns its ID.
+func (w *wal) append(m Message) (uint64, error) {
+	payload, err := m.MarshalJSON()
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal message to JSON: %w", err)
+	}
+
+	id := w.nextID
+	rec := encodeWALRecord(walRecordAppend, id, payload)
+	if len(rec) > walMaxRecordSize {
+		return 0, fmt.Errorf("%w: message is too large", ErrOutboxFull)
+	}
+	if err := w.write(rec, true); err != nil {
+		return 0, err
+	}
+
+	w.nextID++
+	w.segments[len(w.segments)-1].maxID = id
+	return id, nil
+}
+
+// ack records that every entry up to and including id has been delivered, and removes
+// segments which no longer hold undelivered entries.
+func (w *wal) ack(id uint64) error {
+	if err := w.write(encodeWALRecord(walRecordAck, id, nil), false); err != nil {
+		return err
+	}
+	w.acked = id
+	return w.removeDelivered()
+}
+
+// write appends rec to the active segment, starting a new one if it is full. Appends are
+// refused once the log has reached its maximum size; acks, which free space, are not.
+func (w *wal) write(rec []byte, isAppend bool) error {
+	n := int64(len(rec))
+	if isAppend && w.opts.maxSize > 0 && w.size+n > w.opts.maxSize {
+		// the active segment may only hold delivered entries, which can go once it's rotated out
+		if active := w.segments[len(w.segments)-1]; active.maxID <= w.acked && active.size > 0 {
+			if err := w.rotate(); err != nil {
+				return err
+			}
+			if err := w.removeDelivered(); err != nil {
+				return err
+			}
+		}
+		if w.size+n > w.opts.maxSize {
+			return ErrOutboxFull
+		}
+	}
+
+	active := w.segments[len(w.segments)-1]
+	if active.size > 0 && active.size+n > w.opts.segmentSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+		active = w.segments[len(w.segments)-1]
+	}
+
+	if _, err := w.active.Write(rec); err != nil {
+		// don't leave a partial record in the middle of the segment
+		_ = w.active.Truncate(active.size)
+		return fmt.Errorf("failed to write to outbox: %w", err)
+	}
+	active.size += n
+	w.size += n
+	w.dirty = true
+
+	if w.opts.syncWrites {
+		return w.sync()
+	}
+	return nil
+}
+
+// rotate closes the active segment, if any, and starts a new one.
+func (w *wal) rotate() error {
+	var seq uint64 = 1
+	if len(w.segments) > 0 {
+		seq = w.segments[len(w.segments)-1].seq + 1
+	}
+
+	if w.active != nil {
+		if err := w.sync(); err != nil {
+			return err
+		}
+		if err := w.active.Close(); err != nil {
+			return fmt.Errorf("failed to close outbox segment: %w", err)
+		}
+		w.active = nil
+	}
+
+	f, err := os.OpenFile(w.segmentPath(seq), os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox segment: %w", err)
+	}
+	syncDir(w.dir)
+
+	seg := &walSegment{seq: seq}
+	if w.acked > 0 {
+		rec := encodeWALRecord(walRecordAck, w.acked, nil)
+		if _, err := f.Write(rec); err != nil {
+			_ = f.Close()
+			_ = os.Remove(w.segmentPath(seq))
+			return fmt.Errorf("failed to write to outbox: %w", err)
+		}
+		seg.size = int64(len(rec))
+		w.size += seg.size
+		w.dirty = true
+	}
+
+	w.active = f
+	w.segments = append(w.segments, seg)
+	return nil
+}
+
+// removeDelivered deletes the oldest segments while all of their entries have been delivered.
+// The active segment is never removed.
+func (w *wal) removeDelivered() error {
+	removed := false
+	for len(w.segments) > 1 && w.segments[0].maxID <= w.acked {
+		seg := w.segments[0]
+		if err := os.Remove(w.segmentPath(seg.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove outbox segment: %w", err)
+		}
+		w.size -= seg.size
+		w.segments = w.segments[1:]
+		removed = true
+	}
+	if removed {
+		syncDir(w.dir)
+	}
+	return nil
+}
+
+// sync flushes the active segment to disk if it has been written to.
+func (w *wal) sync() error {
+	if !w.dirty || w.active == nil {
+		return nil
+	}
+	if err := w.active.Sync(); err != nil {
+		return fmt.Errorf("failed to sync outbox: %w", err)
+	}
+	w.dirty = false
+	return nil
+}
+
+func (w *wal) close() error {
+	if w.active == nil {
+		return nil
+	}
+	err := w.sync()
+	if cerr := w.active.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("failed to close outbox segment: %w", cerr)
+	}
+	w.active = nil
+	return err
+}
+
+func (w *wal) segmentPath(seq uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", seq, walSegmentExt))
+}
+
+func encodeWALRecord(typ byte, id uint64, payload []byte) []byte {
+	buf := make([]byte, walHeaderSize+9+len(payload))
+	body := buf[walHeaderSize:]
+	body[0] = typ
+	binary.LittleEndian.PutUint64(body[1:9], id)
+	copy(body[9:], payload)
+
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(body, walCRCTable))
+	return buf
+}
+
+// readWALRecord reads the next record from r, returning its total size on disk.
+// It returns io.EOF at a clean end of the segment, and errWALTorn for an incomplete or corrupt record.
+func readWALRecord(r io.Reader) (typ byte, id uint64, payload []byte, n int, err error) {
+	var hdr [walHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, 0, nil, 0, io.EOF
+		}
+		return 0, 0, nil, 0, errWALTorn
+	}
+
+	length := binary.LittleEndian.Uint32(hdr[0:4])
+	if length < 9 || length > walMaxRecordSize {
+		return 0, 0, nil, 0, errWALTorn
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, 0, nil, 0, errWALTorn
+	}
+	if crc32.Checksum(body, walCRCTable) != binary.LittleEndian.Uint32(hdr[4:8]) {
+		return 0, 0, nil, 0, errWALTorn
+	}
+
+	return body[0], binary.LittleEndian.Uint64(body[1:9]), body[9:], walHeaderSize + int(length), nil
+}
@@ -0,0 +1,139 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Since selects the cached messages returned by a poll.
+// See: https://docs.ntfy.sh/subscribe/api/#fetch-cached-messages
+type Since string
+
+const (
+	SinceAll    Since = "all"    // All cached messages.
+	SinceLatest Since = "latest" // Only the most recent message.
+)
+
+// SinceDuration selects messages published within the given duration before now.
+func SinceDuration(d time.Duration) Since {
+	return Since(d.String())
+}
+
+// SinceTime selects messages published at or after t.
+func SinceTime(t time.Time) Since {
+	return Since(strconv.FormatInt(t.Unix(), 10))
+}
+
+// SinceID selects messages published after the message with the given ID.
+func SinceID(id string) Since {
+	return Since(id)
+}
+
+// PollOpts contains the options for a poll. Filters are only applied when set.
+// See: https://docs.ntfy.sh/subscribe/api/#filter-messages
+type PollOpts struct {
+	Since     Since      // Which cached messages to return. Defaults to SinceAll.
+	Scheduled bool       // Also return messages scheduled for delivery in the future.
+	ID        string     // Only return the message with this ID.
+	Message   string     // Only return messages whose body is exactly this text.
+	Title     string     // Only return messages with exactly this title.
+	Priority  []Priority // Only return messages with any of these priorities.
+	Tags      []string   // Only return messages which have all of these tags.
+}
+
+func (o PollOpts) query() url.Values {
+	q := url.Values{}
+	q.Set("poll", "1")
+
+	if o.Since == "" {
+		q.Set("since", string(SinceAll))
+	} else {
+		q.Set("since", string(o.Since))
+	}
+
+	if o.Scheduled {
+		q.Set("scheduled", "1")
+	}
+	if o.ID != "" {
+		q.Set("id", o.ID)
+	}
+	if o.Message != "" {
+		q.Set("message", o.Message)
+	}
+	if o.Title != "" {
+		q.Set("title", o.Title)
+	}
+	if len(o.Priority) > 0 {
+		p := make([]string, len(o.Priority))
+		for i, v := range o.Priority {
+			p[i] = strconv.Itoa(int(v))
+		}
+		q.Set("priority", strings.Join(p, ","))
+	}
+	if len(o.Tags) > 0 {
+		q.Set("tags", strings.Join(o.Tags, ","))
+	}
+
+	return q
+}
+
+// Poll returns the messages currently cached for the given topic, without holding a connection open.
+func (s *subscriber) Poll(ctx context.Context, topic string, opts PollOpts) ([]*MessageEvent, error) {
+	return poll(ctx, s.httpClient, s.server, s.headers, topic, opts)
+}
+
+// poll fetches cached messages from /<topic>/json?poll=1.
+func poll(ctx context.Context, client HttpClient, server url.URL, headers http.Header, topic string, opts PollOpts) ([]*MessageEvent, error) {
+	if topic == "" {
+		return nil, errors.New("topic must not be empty")
+	}
+
+	endpoint := server.JoinPath(topic, "json")
+	endpoint.RawQuery = opts.query().Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header = headers.Clone()
+	req.Header.Del("Content-Type")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to poll: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if resp.Body != nil {
+			defer resp.Body.Close()
+		}
+		return nil, fmt.Errorf("failed to poll: %w", newAPIError(resp))
+	}
+
+	if resp.Body == nil {
+		return nil, fmt.Errorf("response body is nil")
+	}
+	stream := newJSONStream(resp.Body)
+	defer stream.Close()
+
+	var events []*MessageEvent
+	for {
+		e, err := stream.Next()
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read poll response: %w", err)
+		}
+		if e.IsMessage() {
+			events = append(events, e)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package gotfy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollOpts_Query(t *testing.T) {
+	testCases := []struct {
+		name     string
+		arg      PollOpts
+		expected string
+	}{
+		{
+			name:     "defaults",
+			expected: "poll=1&since=all",
+		},
+		{
+			name:     "since duration",
+			arg:      PollOpts{Since: SinceDuration(10 * time.Minute)},
+			expected: "poll=1&since=10m0s",
+		},
+		{
+			name:     "since time",
+			arg:      PollOpts{Since: SinceTime(time.Unix(1685150791, 0))},
+			expected: "poll=1&since=1685150791",
+		},
+		{
+			name:     "since ID",
+			arg:      PollOpts{Since: SinceID("bUhbhgmmbeW0")},
+			expected: "poll=1&since=bUhbhgmmbeW0",
+		},
+		{
+			name: "everything",
+			arg: PollOpts{
+				Since:     SinceLatest,
+				Scheduled: true,
+				ID:        "bUhbhgmmbeW0",
+				Message:   "message",
+				Title:     "title",
+				Priority:  []Priority{PriorityHigh, PriorityMax},
+				Tags:      []string{Warning, "backup"},
+			},
+			expected: "id=bUhbhgmmbeW0&message=message&poll=1&priority=4%2C5&scheduled=1&since=latest&tags=warning%2Cbackup&title=title",
+		},
+	}
+
+	a := assert.New(t)
+	for _, tc := range testCases {
+		a.Equal(tc.expected, tc.arg.query().Encode(), tc.name)
+	}
+}
+
+func Test_Subscriber_Poll(t *testing.T) {
+	r := require.New(t)
+
+	var query url.Values
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/mytopic/json", req.URL.Path)
+		assert.Equal(t, "Bearer tk_0123456789", req.Header.Get("Authorization"))
+		query = req.URL.Query()
+
+		_, _ = fmt.Fprintln(w, `{"id":"m1","time":1685150791,"event":"message","topic":"mytopic","message":"one"}`)
+		_, _ = fmt.Fprintln(w, `{"id":"m2","time":1685150792,"event":"message","topic":"mytopic","message":"two"}`)
+	}, SubscriberOpts{Auth: AccessToken("tk_0123456789")})
+
+	events, err := sut.Poll(context.Background(), "mytopic", PollOpts{Scheduled: true})
+	r.NoError(err)
+	r.Equal("1", query.Get("poll"))
+	r.Equal("all", query.Get("since"))
+	r.Equal("1", query.Get("scheduled"))
+
+	r.Len(events, 2)
+	r.Equal("one", events[0].Message)
+	r.Equal("two", events[1].Message)
+}
+
+func Test_Subscriber_PollEmpty(t *testing.T) {
+	r := require.New(t)
+
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {}, SubscriberOpts{})
+
+	events, err := sut.Poll(context.Background(), "mytopic", PollOpts{})
+	r.NoError(err)
+	r.Empty(events)
+}
+
+func Test_Subscriber_PollErrorWithoutBody(t *testing.T) {
+	r := require.New(t)
+
+	c := FakeHttpClient{Response: func() (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusUnauthorized}, nil
+	}}
+	sut := NewSubscriber(SubscriberOpts{HttpClient: &c})
+
+	_, err := sut.Poll(context.Background(), "mytopic", PollOpts{})
+	var apiErr *APIError
+	r.ErrorAs(err, &apiErr)
+	r.Equal(http.StatusUnauthorized, apiErr.HTTPCode)
+}
@@ -1,13 +1,14 @@
 package gotfy
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // Publisher sends notification messages to a Ntfy server.
@@ -16,10 +17,27 @@ type Publisher interface {
 	Send(ctx context.Context, m Message) (*SendResponse, error)
 }
 
+// DefaultMaxResponseSize is used when PublisherOpts.MaxResponseSize is zero.
+const DefaultMaxResponseSize = 1 << 20
+
+// maxDrainSize bounds how much of an unread response body is discarded before closing it,
+// so that the connection can be reused. Longer bodies are abandoned along with their connection.
+const maxDrainSize = 64 << 10
+
+// ErrResponseTooLarge is returned when the server's response exceeds PublisherOpts.MaxResponseSize.
+var ErrResponseTooLarge = errors.New("response body too large")
+
 type publisher struct {
-	server     url.URL
-	headers    http.Header
-	httpClient HttpClient
+	server          url.URL
+	headers         http.Header
+	httpClient      HttpClient
+	maxResponseSize int64
+	retry           *RetryPolicy
+	idempotency     *IdempotencyOpts
+	limiter         *rateLimiter
+	wire            WireFormat
+	maxDelay        time.Duration
+	now             func() time.Time
 }
 
 type HttpClient interface {
@@ -33,20 +51,33 @@ type PublisherOpts struct {
 	Auth       Authorization
 	Headers    http.Header
 	HttpClient HttpClient
+
+	// MaxResponseSize is the largest response body the publisher reads, in bytes.
+	// If zero, DefaultMaxResponseSize is used.
+	MaxResponseSize int64
+
+	// Retry, if set, makes Send retry transient failures. By default, each message is sent once.
+	Retry *RetryPolicy
+
+	// Idempotency, if set, makes retries duplicate-safe; see IdempotencyOpts.
+	Idempotency *IdempotencyOpts
+
+	// RateLimit, if set, makes Send keep to the given rate limits; see RateLimitOpts.
+	RateLimit *RateLimitOpts
+
+	// Wire selects how messages are encoded. Defaults to WireJSON.
+	Wire WireFormat
+
+	// MaxDelay is the furthest ahead a message's delivery may be scheduled, matching the server's
+	// message-delay-limit. If zero, DefaultMaxDelay is used; if negative, it isn't checked.
+	MaxDelay time.Duration
 }
 
 // NewPublisher creates a publisher for the given Ntfy server URL.
 func NewPublisher(opts PublisherOpts) Publisher {
 	retv := publisher{}
 
-	if opts.Server == nil || opts.Server.String() == "" {
-		retv.server = url.URL{
-			Scheme: "https",
-			Host:   "ntfy.sh",
-		}
-	} else {
-		retv.server = *opts.Server
-	}
+	retv.server = serverOrDefault(opts.Server)
 
 	if opts.Headers == nil {
 		retv.headers = make(http.Header)
@@ -66,36 +97,97 @@ func NewPublisher(opts PublisherOpts) Publisher {
 		retv.httpClient = opts.HttpClient
 	}
 
+	if opts.MaxResponseSize <= 0 {
+		retv.maxResponseSize = DefaultMaxResponseSize
+	} else {
+		retv.maxResponseSize = opts.MaxResponseSize
+	}
+
+	if opts.Retry != nil {
+		retry := *opts.Retry
+		retv.retry = &retry
+	}
+
+	if opts.Idempotency != nil {
+		idempotency := *opts.Idempotency
+		retv.idempotency = &idempotency
+	}
+
+	if opts.RateLimit != nil {
+		retv.limiter = newRateLimiter(*opts.RateLimit)
+	}
+
+	retv.wire = opts.Wire
+
+	if opts.MaxDelay == 0 {
+		retv.maxDelay = DefaultMaxDelay
+	} else {
+		retv.maxDelay = opts.MaxDelay
+	}
+	retv.now = time.Now
+
 	return &retv
 }
 
-// Send publishes the given message to the configured Ntfy server.
+// Send publishes the given message to the configured Ntfy server, keeping to the configured
+// rate limits and retrying transient failures according to the configured RetryPolicy.
 func (p *publisher) Send(ctx context.Context, m Message) (*SendResponse, error) {
-	buf, err := json.Marshal(m)
+	if err := m.checkSchedule(p.now(), p.maxDelay); err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	if p.limiter != nil {
+		if err := p.limiter.wait(ctx, &m); err != nil {
+			return nil, fmt.Errorf("failed to send message: %w", err)
+		}
+	}
+
+	resp, err := p.sendWithRetry(ctx, &m)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal message to JSON: %w", err)
+		return nil, err
 	}
+	resp.Server = p.server.String()
+	return resp, nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.server.String(), bytes.NewReader(buf))
+// sendOnce makes a single attempt at publishing m.
+// Failures which may succeed when retried are returned as a *retryableError.
+func (p *publisher) sendOnce(ctx context.Context, m *Message) (*SendResponse, error) {
+	req, err := p.newRequest(ctx, m)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build request: %w", err)
+		return nil, err
 	}
-	req.Header = p.headers.Clone()
+	return p.do(req)
+}
 
+// do sends a publish request and reads the server's response.
+// Failures which may succeed when retried are returned as a *retryableError.
+func (p *publisher) do(req *http.Request) (*SendResponse, error) {
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send message: %w", err)
+		// the request may have reached the server before the connection failed
+		return nil, &retryableError{err: fmt.Errorf("failed to send message: %w", err), maybeDelivered: true}
 	}
+	defer closeBody(resp)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("failed to send message: HTTP %d", resp.StatusCode)
+		apiErr := newAPIError(resp)
+		err := fmt.Errorf("failed to send message: %w", apiErr)
+		if isTransient(apiErr) {
+			return nil, &retryableError{
+				err:            err,
+				retryAfter:     parseRetryAfter(resp.Header.Get("Retry-After")),
+				maybeDelivered: apiErr.HTTPCode >= 500,
+			}
+		}
+		return nil, err
 	}
 
 	if resp.Body == nil {
 		return nil, fmt.Errorf("response body is nil")
 	}
 
-	buf, err = io.ReadAll(resp.Body)
+	buf, err := readBody(resp, p.maxResponseSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -107,3 +199,42 @@ func (p *publisher) Send(ctx context.Context, m Message) (*SendResponse, error)
 
 	return &pubResp, nil
 }
+
+// readBody reads the response body, failing with ErrResponseTooLarge if it is longer than limit.
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	if resp.ContentLength > limit {
+		return nil, ErrResponseTooLarge
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(buf)) > limit {
+		return nil, ErrResponseTooLarge
+	}
+	return buf, nil
+}
+
+// closeBody discards what remains of the response body, up to maxDrainSize, and closes it,
+// allowing the underlying connection to be reused. Upgraded connections are closed immediately.
+func closeBody(resp *http.Response) {
+	if resp.Body == nil {
+		return
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize))
+	}
+	_ = resp.Body.Close()
+}
+
+// serverOrDefault returns a copy of the given server URL, or the public ntfy.sh server if none is given.
+func serverOrDefault(server *url.URL) url.URL {
+	if server == nil || server.String() == "" {
+		return url.URL{
+			Scheme: "https",
+			Host:   "ntfy.sh",
+		}
+	}
+	return *server
+}
@@ -2,7 +2,12 @@ package gotfy
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -34,6 +39,17 @@ func (m *FakeHttpClient) Do(req *http.Request) (*http.Response, error) {
 	}, nil
 }
 
+// newTestServer starts a server which serves requests with handler until the test ends,
+// and returns its URL.
+func newTestServer(t *testing.T, handler http.Handler) *url.URL {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	return u
+}
+
 func Test_Publisher_SetsJSONHeaders(t *testing.T) {
 	r := require.New(t)
 	c := FakeHttpClient{}
@@ -61,3 +77,104 @@ func Test_Publisher_AuthToken(t *testing.T) {
 	}
 	_, _ = sut.Send(context.Background(), Message{})
 }
+
+// countingHttpClient returns canned responses and tracks whether their bodies are closed.
+type countingHttpClient struct {
+	status int
+	body   string
+
+	mu     sync.Mutex
+	bodies []*countingBody
+}
+
+type countingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *countingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func (c *countingHttpClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	body := &countingBody{Reader: strings.NewReader(c.body)}
+	c.bodies = append(c.bodies, body)
+	return &http.Response{
+		StatusCode:    c.status,
+		Body:          body,
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
+
+func (c *countingHttpClient) unclosed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for _, b := range c.bodies {
+		if !b.closed {
+			n++
+		}
+	}
+	return n
+}
+
+func Test_Publisher_ClosesResponseBodies(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status int
+		body   string
+		err    error
+	}{
+		{
+			name:   "success",
+			status: 200,
+			body:   `{"id":"bUhbhgmmbeW0","time":1685150791,"event":"message","topic":"mytopic"}`,
+		},
+		{
+			name:   "error",
+			status: 403,
+			body:   `{"code":40301,"http":403,"error":"forbidden"}`,
+			err:    ErrForbidden,
+		},
+		{
+			name:   "decode failure",
+			status: 200,
+			body:   `<html>not JSON</html>`,
+		},
+		{
+			name:   "too large",
+			status: 200,
+			body:   `{"id":"` + strings.Repeat("x", 100) + `"}`,
+			err:    ErrResponseTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			c := &countingHttpClient{status: tc.status, body: tc.body}
+			sut := NewPublisher(PublisherOpts{HttpClient: c, MaxResponseSize: 100})
+
+			for i := 0; i < 10; i++ {
+				_, err := sut.Send(context.Background(), Message{Topic: "mytopic"})
+				switch {
+				case tc.err != nil:
+					r.ErrorIs(err, tc.err)
+				case tc.name == "success":
+					r.NoError(err)
+				default:
+					r.Error(err)
+				}
+			}
+
+			r.Len(c.bodies, 10)
+			r.Zero(c.unclosed())
+		})
+	}
+}
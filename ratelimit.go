@@ -0,0 +1,213 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRateLimitExceeded is returned by Send when a message would exceed the publisher's own
+// rate limits; the message is not sent to the server. See RateLimitOpts.
+var ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
+// RateLimit describes a token bucket: each message takes a token, and tokens are added
+// back one every Interval, up to Burst. The zero value does not limit anything.
+type RateLimit struct {
+	// Burst is the number of tokens the bucket holds. If zero while Interval is set, no messages are allowed.
+	Burst int
+
+	// Interval is how often a token is added. If zero, the bucket is unlimited.
+	Interval time.Duration
+
+	// Used is the number of tokens already taken when the publisher is created.
+	// By default, the bucket starts full.
+	Used int
+}
+
+// RateLimitOpts configures the rate limits a Publisher applies to itself, so as to stay within the
+// server's limits rather than running into 429 responses. Every message takes a token from Messages;
+// messages with Email or Call set also take one from Emails or Calls respectively.
+//
+// Limits are applied once per Send, before the first attempt; retries do not take further tokens.
+// Use Account.RateLimits to derive limits from those the server reports.
+type RateLimitOpts struct {
+	Messages RateLimit
+	Emails   RateLimit
+	Calls    RateLimit
+
+	// FailFast makes Send return ErrRateLimitExceeded when a message would exceed a limit.
+	// By default, Send waits for tokens to become available, unless the context ends first.
+	FailFast bool
+}
+
+// RateLimits returns rate limits matching the account's daily message, e-mail and call limits,
+// allowing for what has been used already today. Tokens are added back evenly over the day.
+// Limits the server doesn't report, as zero or less, are left unlimited.
+//
+// These are daily quotas only. They don't model the server's per-visitor request limiter, which
+// allows a burst of requests and then a steady rate, and is what produces 42901 responses
+// (ErrRateLimited); configure RateLimitOpts.Messages to match the server's settings for that.
+func (a *Account) RateLimits() RateLimitOpts {
+	daily := func(limit, used int64) RateLimit {
+		if limit <= 0 {
+			return RateLimit{}
+		}
+		if used > limit {
+			used = limit
+		}
+		return RateLimit{
+			Burst:    int(limit),
+			Interval: 24 * time.Hour / time.Duration(limit),
+			Used:     int(used),
+		}
+	}
+
+	return RateLimitOpts{
+		Messages: daily(a.Limits.Messages, a.Stats.Messages),
+		Emails:   daily(a.Limits.Emails, a.Stats.Emails),
+		Calls:    daily(a.Limits.Calls, a.Stats.Calls),
+	}
+}
+
+type tokenBucket struct {
+	name     string
+	burst    float64
+	interval time.Duration
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(name string, l RateLimit, now time.Time) *tokenBucket {
+	if l.Interval <= 0 {
+		return nil
+	}
+	b := &tokenBucket{
+		name:     name,
+		burst:    float64(l.Burst),
+		interval: l.Interval,
+		tokens:   float64(l.Burst - l.Used),
+		last:     now,
+	}
+	if b.tokens < 0 {
+		b.tokens = 0
+	}
+	return b
+}
+
+// advance adds the tokens accrued since the bucket was last updated.
+func (b *tokenBucket) advance(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(b.interval)
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+// wait returns how long until the bucket holds a full token.
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(b.interval))
+}
+
+type rateLimiter struct {
+	mu       sync.Mutex
+	messages *tokenBucket
+	emails   *tokenBucket
+	calls    *tokenBucket
+	failFast bool
+	now      func() time.Time
+}
+
+func newRateLimiter(opts RateLimitOpts) *rateLimiter {
+	now := time.Now()
+	return &rateLimiter{
+		messages: newTokenBucket("messages", opts.Messages, now),
+		emails:   newTokenBucket("emails", opts.Emails, now),
+		calls:    newTokenBucket("calls", opts.Calls, now),
+		failFast: opts.FailFast,
+		now:      time.Now,
+	}
+}
+
+// wait takes a token for m from each bucket that applies to it, waiting for them if needed.
+func (l *rateLimiter) wait(ctx context.Context, m *Message) error {
+	buckets := make([]*tokenBucket, 0, 3)
+	for _, b := range []*tokenBucket{l.messages, l.emailsFor(m), l.callsFor(m)} {
+		if b != nil {
+			buckets = append(buckets, b)
+		}
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := l.now()
+	var delay time.Duration
+	for _, b := range buckets {
+		b.advance(now)
+		if b.burst < 1 {
+			l.mu.Unlock()
+			return fmt.Errorf("%w: no %s allowed", ErrRateLimitExceeded, b.name)
+		}
+		if d := b.wait(); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 && l.failFast {
+		l.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrRateLimitExceeded, firstEmpty(buckets).name)
+	}
+	if deadline, ok := ctx.Deadline(); ok && delay > 0 && time.Until(deadline) < delay {
+		l.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrRateLimitExceeded, firstEmpty(buckets).name)
+	}
+
+	// reserve the tokens now, so that later messages queue up behind this one
+	for _, b := range buckets {
+		b.tokens--
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	if err := sleepContext(ctx, delay); err != nil {
+		l.mu.Lock()
+		for _, b := range buckets {
+			b.tokens++
+		}
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (l *rateLimiter) emailsFor(m *Message) *tokenBucket {
+	if m.Email == "" {
+		return nil
+	}
+	return l.emails
+}
+
+func (l *rateLimiter) callsFor(m *Message) *tokenBucket {
+	if m.Call == "" {
+		return nil
+	}
+	return l.calls
+}
+
+func firstEmpty(buckets []*tokenBucket) *tokenBucket {
+	for _, b := range buckets {
+		if b.tokens < 1 {
+			return b
+		}
+	}
+	return buckets[0]
+}
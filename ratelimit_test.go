@@ -0,0 +1,119 @@
+package gotfy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitTestPublisher(opts RateLimitOpts) (Publisher, *int) {
+	calls := 0
+	c := &FakeHttpClient{Response: func() (*http.Response, error) {
+		calls++
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"id":"bUhbhgmmbeW0","event":"message"}`)),
+		}, nil
+	}}
+	return NewPublisher(PublisherOpts{HttpClient: c, RateLimit: &opts}), &calls
+}
+
+func Test_Publisher_RateLimitFailFast(t *testing.T) {
+	r := require.New(t)
+
+	sut, calls := newRateLimitTestPublisher(RateLimitOpts{
+		Messages: RateLimit{Burst: 3, Interval: time.Hour},
+		Emails:   RateLimit{Burst: 1, Interval: time.Hour},
+		Calls:    RateLimit{Interval: time.Hour},
+		FailFast: true,
+	})
+
+	_, err := sut.Send(context.Background(), Message{Topic: "mytopic", Email: "phil@example.com"})
+	r.NoError(err)
+
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic", Email: "phil@example.com"})
+	r.ErrorIs(err, ErrRateLimitExceeded)
+	r.EqualError(err, "failed to send message: rate limit exceeded: emails")
+
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic", Call: "+12223334444"})
+	r.EqualError(err, "failed to send message: rate limit exceeded: no calls allowed")
+
+	// the rejected messages took no tokens
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic"})
+	r.NoError(err)
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic"})
+	r.NoError(err)
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic"})
+	r.EqualError(err, "failed to send message: rate limit exceeded: messages")
+
+	r.Equal(3, *calls)
+}
+
+func Test_Publisher_RateLimitWaits(t *testing.T) {
+	r := require.New(t)
+
+	sut, calls := newRateLimitTestPublisher(RateLimitOpts{
+		Messages: RateLimit{Burst: 1, Interval: 50 * time.Millisecond},
+	})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := sut.Send(context.Background(), Message{Topic: "mytopic"})
+		r.NoError(err)
+	}
+	r.GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+	r.Equal(3, *calls)
+
+	// a deadline too near to wait for fails right away
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := sut.Send(ctx, Message{Topic: "mytopic"})
+	r.ErrorIs(err, ErrRateLimitExceeded)
+	r.Equal(3, *calls)
+}
+
+func Test_RateLimiter_Refills(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Unix(1685150791, 0)
+	sut := newRateLimiter(RateLimitOpts{
+		Messages: RateLimit{Burst: 2, Interval: time.Minute, Used: 1},
+		FailFast: true,
+	})
+	sut.now = func() time.Time { return now }
+	sut.messages.last = now
+
+	m := &Message{Topic: "mytopic"}
+	r.NoError(sut.wait(context.Background(), m))
+	r.ErrorIs(sut.wait(context.Background(), m), ErrRateLimitExceeded)
+
+	now = now.Add(59 * time.Second)
+	r.ErrorIs(sut.wait(context.Background(), m), ErrRateLimitExceeded)
+	now = now.Add(time.Second)
+	r.NoError(sut.wait(context.Background(), m))
+
+	// never holds more than Burst tokens
+	now = now.Add(time.Hour)
+	r.NoError(sut.wait(context.Background(), m))
+	r.NoError(sut.wait(context.Background(), m))
+	r.ErrorIs(sut.wait(context.Background(), m), ErrRateLimitExceeded)
+}
+
+func Test_RateLimiter_ReturnsTokensOnCancel(t *testing.T) {
+	r := require.New(t)
+
+	sut := newRateLimiter(RateLimitOpts{Messages: RateLimit{Burst: 1, Interval: time.Hour, Used: 1}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	r.ErrorIs(sut.wait(ctx, &Message{}), context.Canceled)
+
+	sut.mu.Lock()
+	defer sut.mu.Unlock()
+	r.InDelta(0, sut.messages.tokens, 0.01)
+}
@@ -0,0 +1,163 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryAttempts is used when RetryPolicy.MaxAttempts is zero.
+const DefaultRetryAttempts = 3
+
+// RetryPolicy controls how a Publisher retries sends which fail transiently:
+// connection errors, 5xx responses, 408 and 429 rate limiting responses.
+// Errors which retrying cannot fix, such as ErrUnauthorized or ErrEmailQuotaExceeded, are returned immediately.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// If zero, DefaultRetryAttempts is used.
+	MaxAttempts int
+
+	// Backoff controls the delay between attempts. If nil, DefaultBackoff is used.
+	// A longer delay requested by the server in a Retry-After header takes precedence.
+	Backoff *Backoff
+
+	// AttemptTimeout, if set, bounds each attempt, so that a request which hangs is
+	// abandoned and retried while the caller's context still has time left.
+	AttemptTimeout time.Duration
+}
+
+// retryableError wraps an error after which sending the same message again may succeed.
+type retryableError struct {
+	err            error
+	retryAfter     time.Duration // Delay requested by the server, if any.
+	maybeDelivered bool          // Whether the server may have accepted the message despite the error.
+}
+
+func (r *retryableError) Error() string {
+	return r.err.Error()
+}
+
+func (r *retryableError) Unwrap() error {
+	return r.err
+}
+
+// sendWithRetry publishes m, retrying transient failures according to the publisher's RetryPolicy.
+// It gives up early rather than sleep past ctx's deadline.
+func (p *publisher) sendWithRetry(ctx context.Context, m *Message) (*SendResponse, error) {
+	maxAttempts := 1
+	backoff := DefaultBackoff
+	var attemptTimeout time.Duration
+	if p.retry != nil {
+		maxAttempts = p.retry.MaxAttempts
+		if maxAttempts == 0 {
+			maxAttempts = DefaultRetryAttempts
+		}
+		if p.retry.Backoff != nil {
+			backoff = *p.retry.Backoff
+		}
+		attemptTimeout = p.retry.AttemptTimeout
+	}
+
+	var fingerprint string
+	if p.idempotency != nil {
+		var err error
+		if fingerprint, err = newFingerprint(); err != nil {
+			return nil, err
+		}
+		p.idempotency.embed(m, fingerprint)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := p.sendAttempt(ctx, m, attemptTimeout)
+		if err == nil {
+			resp.Attempts = attempt
+			return resp, nil
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || ctx.Err() != nil {
+			return nil, withAttempts(err, attempt)
+		}
+		err = retryable.err
+
+		if attempt >= maxAttempts {
+			return nil, withAttempts(err, attempt)
+		}
+
+		delay := backoff.Delay(attempt - 1)
+		if retryable.retryAfter > delay {
+			delay = retryable.retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, withAttempts(err, attempt)
+		}
+
+		if sleepContext(ctx, delay) != nil {
+			return nil, withAttempts(err, attempt)
+		}
+
+		if fingerprint != "" && retryable.maybeDelivered {
+			if e := p.reconcile(ctx, m, fingerprint); e != nil {
+				return &SendResponse{MessageEvent: *e, Attempts: attempt, Reconciled: true}, nil
+			}
+		}
+	}
+}
+
+// sendAttempt makes a single attempt at publishing m, bounded by timeout if it is positive.
+func (p *publisher) sendAttempt(ctx context.Context, m *Message, timeout time.Duration) (*SendResponse, error) {
+	if timeout <= 0 {
+		return p.sendOnce(ctx, m)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return p.sendOnce(ctx, m)
+}
+
+// withAttempts annotates err with the number of attempts made, if there was more than one.
+func withAttempts(err error, attempts int) error {
+	if attempts <= 1 {
+		return err
+	}
+	return fmt.Errorf("%w (gave up after %d attempts)", err, attempts)
+}
+
+// isTransient reports whether the server may accept the same request if it is retried later.
+func isTransient(e *APIError) bool {
+	switch {
+	case e.HTTPCode >= 500:
+		return true
+	case e.HTTPCode == http.StatusRequestTimeout:
+		return true
+	case e.HTTPCode == http.StatusTooManyRequests:
+		// quota errors, e.g. for e-mails or phone calls, persist until the quota resets
+		return e.Code == 0 || e.Is(ErrRateLimited)
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or as an HTTP date.
+// It returns zero if the header is missing or invalid.
+// See: https://www.rfc-editor.org/rfc/rfc9110#field.retry-after
+func parseRetryAfter(s string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,132 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fastBackoff = &Backoff{Initial: time.Millisecond, Max: time.Millisecond}
+
+func newRetryTestPublisher(t *testing.T, handler http.HandlerFunc, retry *RetryPolicy) Publisher {
+	return NewPublisher(PublisherOpts{Server: newTestServer(t, handler), Retry: retry})
+}
+
+func Test_Publisher_RetriesTransientErrors(t *testing.T) {
+	r := require.New(t)
+
+	var calls atomic.Int32
+	sut := newRetryTestPublisher(t, func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		assert.Equal(t, `{"topic":"mytopic","message":"hi"}`, string(body))
+
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = io.WriteString(w, `{"code":42901,"http":429,"error":"limit reached: too many requests"}`)
+		default:
+			_, _ = io.WriteString(w, `{"id":"bUhbhgmmbeW0","time":1685150791,"event":"message","topic":"mytopic"}`)
+		}
+	}, &RetryPolicy{MaxAttempts: 5, Backoff: fastBackoff})
+
+	resp, err := sut.Send(context.Background(), Message{Topic: "mytopic", Message: "hi"})
+	r.NoError(err)
+	r.Equal("bUhbhgmmbeW0", resp.ID)
+	r.Equal(3, resp.Attempts)
+	r.Equal(int32(3), calls.Load())
+}
+
+func Test_Publisher_GivesUpAfterMaxAttempts(t *testing.T) {
+	r := require.New(t)
+
+	var calls atomic.Int32
+	sut := newRetryTestPublisher(t, func(w http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, &RetryPolicy{MaxAttempts: 3, Backoff: fastBackoff})
+
+	_, err := sut.Send(context.Background(), Message{Topic: "mytopic"})
+	var apiErr *APIError
+	r.True(errors.As(err, &apiErr))
+	r.Equal(http.StatusServiceUnavailable, apiErr.HTTPCode)
+	r.Contains(err.Error(), "gave up after 3 attempts")
+	r.Equal(int32(3), calls.Load())
+}
+
+func Test_Publisher_DoesNotRetryPermanentErrors(t *testing.T) {
+	r := require.New(t)
+
+	var calls atomic.Int32
+	sut := newRetryTestPublisher(t, func(w http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"code":42902,"http":429,"error":"limit reached: too many emails"}`)
+	}, &RetryPolicy{Backoff: fastBackoff})
+
+	_, err := sut.Send(context.Background(), Message{Topic: "mytopic", Email: "phil@example.com"})
+	r.ErrorIs(err, ErrEmailQuotaExceeded)
+	r.Equal(int32(1), calls.Load())
+}
+
+func Test_Publisher_RetryAfterRespectsDeadline(t *testing.T) {
+	r := require.New(t)
+
+	var calls atomic.Int32
+	sut := newRetryTestPublisher(t, func(w http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}, &RetryPolicy{Backoff: fastBackoff})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := sut.Send(ctx, Message{Topic: "mytopic"})
+	r.Error(err)
+	r.Less(time.Since(start), time.Second, "should not wait for a Retry-After beyond the deadline")
+	r.Equal(int32(1), calls.Load())
+}
+
+func Test_Publisher_RetriesConnectionErrors(t *testing.T) {
+	r := require.New(t)
+
+	calls := 0
+	c := &FakeHttpClient{Response: func() (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"id":"bUhbhgmmbeW0","event":"message"}`)),
+		}, nil
+	}}
+	sut := NewPublisher(PublisherOpts{HttpClient: c, Retry: &RetryPolicy{Backoff: fastBackoff}})
+
+	resp, err := sut.Send(context.Background(), Message{Topic: "mytopic"})
+	r.NoError(err)
+	r.Equal(2, resp.Attempts)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(time.Duration(0), parseRetryAfter(""))
+	r.Equal(time.Duration(0), parseRetryAfter("soon"))
+	r.Equal(120*time.Second, parseRetryAfter("120"))
+
+	d := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	r.Greater(d, 59*time.Minute)
+	r.LessOrEqual(d, time.Hour)
+}
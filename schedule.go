@@ -0,0 +1,96 @@
+package gotfy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits on how far ahead a message's delivery may be scheduled. Ntfy's maximum is configured by
+// the server's message-delay-limit option; DefaultMaxDelay is its default.
+// See: https://docs.ntfy.sh/publish/#scheduled-delivery
+const (
+	MinDelay        = 10 * time.Second
+	DefaultMaxDelay = 3 * 24 * time.Hour
+)
+
+// Errors returned by Send for messages whose scheduled delivery Ntfy would reject.
+var (
+	ErrDelayTooShort    = errors.New("delivery is scheduled too soon")
+	ErrDelayTooLong     = errors.New("delivery is scheduled too far ahead")
+	ErrScheduleConflict = errors.New("only one of Delay, DeliverAt and Schedule may be set")
+)
+
+// unixTimestamp matches a schedule expression which Ntfy takes as a Unix timestamp.
+var unixTimestamp = regexp.MustCompile(`^\d+$`)
+
+// delayValue returns the scheduled delivery of m as sent to Ntfy, or "" if it's to be delivered now.
+func (m *Message) delayValue() (string, error) {
+	n := 0
+	for _, set := range []bool{m.Delay > 0, !m.DeliverAt.IsZero(), strings.TrimSpace(m.Schedule) != ""} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return "", ErrScheduleConflict
+	}
+
+	switch {
+	case m.Delay > 0:
+		return m.Delay.String(), nil
+	case !m.DeliverAt.IsZero():
+		return strconv.FormatInt(m.DeliverAt.Unix(), 10), nil
+	default:
+		return strings.TrimSpace(m.Schedule), nil
+	}
+}
+
+// checkSchedule returns an error if m is scheduled for delivery sooner than MinDelay or
+// later than max from now. A max of zero or less isn't checked. Schedule expressions in
+// natural language are left for the server to check.
+func (m *Message) checkSchedule(now time.Time, max time.Duration) error {
+	if _, err := m.delayValue(); err != nil {
+		return err
+	}
+
+	var d time.Duration
+	switch s := strings.TrimSpace(m.Schedule); {
+	case m.Delay > 0:
+		d = m.Delay
+	case !m.DeliverAt.IsZero():
+		d = m.DeliverAt.Sub(now)
+	case unixTimestamp.MatchString(s):
+		ts, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid schedule %q: %w", s, err)
+		}
+		d = time.Unix(ts, 0).Sub(now)
+	case s != "":
+		parsed, err := parseDelay(s)
+		if err != nil {
+			return nil
+		}
+		d = parsed
+	default:
+		return nil
+	}
+
+	switch {
+	case d <= 0:
+		return fmt.Errorf("%w: delivery time is in the past", ErrDelayTooShort)
+	case d < MinDelay:
+		return fmt.Errorf("%w: delivery must be at least %s away, but is %s away", ErrDelayTooShort, MinDelay, d.Round(time.Second))
+	case max > 0 && d > max:
+		return fmt.Errorf("%w: delivery must be at most %s away, but is %s away", ErrDelayTooLong, max, d.Round(time.Second))
+	}
+	return nil
+}
+
+// isScheduleError reports whether err rejects a message's scheduled delivery before it was sent.
+func isScheduleError(err error) bool {
+	return errors.Is(err, ErrDelayTooShort) || errors.Is(err, ErrDelayTooLong) || errors.Is(err, ErrScheduleConflict)
+}
@@ -0,0 +1,121 @@
+package gotfy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage_CheckSchedule(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	testCases := []struct {
+		name     string
+		arg      Message
+		max      time.Duration
+		expected error
+	}{
+		{name: "not scheduled", arg: Message{}},
+		{name: "delay", arg: Message{Delay: 30 * time.Minute}},
+		{name: "minimum delay", arg: Message{Delay: MinDelay}},
+		{name: "delay too short", arg: Message{Delay: 5 * time.Second}, expected: ErrDelayTooShort},
+		{name: "maximum delay", arg: Message{Delay: DefaultMaxDelay}, max: DefaultMaxDelay},
+		{name: "delay too long", arg: Message{Delay: DefaultMaxDelay + time.Second}, max: DefaultMaxDelay, expected: ErrDelayTooLong},
+		{name: "delay unchecked", arg: Message{Delay: 30 * 24 * time.Hour}},
+		{name: "deliver at", arg: Message{DeliverAt: now.Add(time.Hour)}, max: DefaultMaxDelay},
+		{name: "deliver at too soon", arg: Message{DeliverAt: now.Add(time.Second)}, expected: ErrDelayTooShort},
+		{name: "deliver at in the past", arg: Message{DeliverAt: now.Add(-time.Hour)}, expected: ErrDelayTooShort},
+		{name: "deliver at too late", arg: Message{DeliverAt: now.Add(4 * 24 * time.Hour)}, max: DefaultMaxDelay, expected: ErrDelayTooLong},
+		{name: "schedule duration", arg: Message{Schedule: "2 hours"}, max: DefaultMaxDelay},
+		{name: "schedule duration too long", arg: Message{Schedule: "4d"}, max: DefaultMaxDelay, expected: ErrDelayTooLong},
+		{name: "schedule timestamp", arg: Message{Schedule: "1700003600"}, max: DefaultMaxDelay},
+		{name: "schedule timestamp too soon", arg: Message{Schedule: "1700000005"}, expected: ErrDelayTooShort},
+		{name: "schedule expression", arg: Message{Schedule: "tomorrow, 10am"}, max: DefaultMaxDelay},
+		{name: "conflict", arg: Message{Delay: time.Hour, DeliverAt: now.Add(time.Hour)}, expected: ErrScheduleConflict},
+		{name: "conflict with schedule", arg: Message{DeliverAt: now.Add(time.Hour), Schedule: "tomorrow"}, expected: ErrScheduleConflict},
+	}
+
+	for _, tc := range testCases {
+		err := tc.arg.checkSchedule(now, tc.max)
+		if tc.expected == nil {
+			assert.NoError(t, err, tc.name)
+		} else {
+			assert.ErrorIs(t, err, tc.expected, tc.name)
+		}
+	}
+}
+
+func TestMessage_CheckSchedule_Errors(t *testing.T) {
+	r := require.New(t)
+	now := time.Unix(1700000000, 0)
+
+	m := Message{Delay: 3 * time.Second}
+	r.EqualError(m.checkSchedule(now, 0), "delivery is scheduled too soon: delivery must be at least 10s away, but is 3s away")
+
+	m = Message{DeliverAt: now.Add(-time.Minute)}
+	r.EqualError(m.checkSchedule(now, 0), "delivery is scheduled too soon: delivery time is in the past")
+
+	m = Message{DeliverAt: now.Add(96 * time.Hour)}
+	r.EqualError(m.checkSchedule(now, DefaultMaxDelay), "delivery is scheduled too far ahead: delivery must be at most 72h0m0s away, but is 96h0m0s away")
+}
+
+func Test_Publisher_ChecksSchedule(t *testing.T) {
+	r := require.New(t)
+
+	requests := 0
+	client := &FakeHttpClient{Response: func() (*http.Response, error) {
+		requests++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"id":"bUhbhgmmbeW0","time":1685150791,"event":"message","topic":"mytopic"}`)),
+		}, nil
+	}}
+
+	sut := NewPublisher(PublisherOpts{HttpClient: client})
+	_, err := sut.Send(context.Background(), Message{Topic: "mytopic", DeliverAt: time.Now().Add(5 * 24 * time.Hour)})
+	r.ErrorIs(err, ErrDelayTooLong)
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic", Delay: time.Second})
+	r.ErrorIs(err, ErrDelayTooShort)
+	r.Zero(requests)
+
+	sut = NewPublisher(PublisherOpts{HttpClient: client, MaxDelay: 7 * 24 * time.Hour})
+	_, err = sut.Send(context.Background(), Message{Topic: "mytopic", DeliverAt: time.Now().Add(5 * 24 * time.Hour)})
+	r.NoError(err)
+	r.Equal(1, requests)
+}
+
+func Test_Publisher_SendsDeliverAt(t *testing.T) {
+	r := require.New(t)
+	deliverAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	var header, body string
+	client := &FakeHttpClient{
+		CheckDo: func(req *http.Request) {
+			header = req.Header.Get("X-Delay")
+			buf, _ := io.ReadAll(req.Body)
+			body = string(buf)
+		},
+		Response: func() (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"id":"bUhbhgmmbeW0","time":1685150791,"event":"message","topic":"mytopic"}`)),
+			}, nil
+		},
+	}
+	m := Message{Topic: "mytopic", Message: "Maintenance starts now", DeliverAt: deliverAt}
+
+	_, err := NewPublisher(PublisherOpts{HttpClient: client}).Send(context.Background(), m)
+	r.NoError(err)
+	r.Contains(body, `"delay":"`+strconv.FormatInt(deliverAt.Unix(), 10)+`"`)
+
+	_, err = NewPublisher(PublisherOpts{HttpClient: client, Wire: WireHeaders}).Send(context.Background(), m)
+	r.NoError(err)
+	r.Equal(strconv.FormatInt(deliverAt.Unix(), 10), header)
+}
@@ -2,17 +2,18 @@ package gotfy
 
 import (
 	"encoding/json"
+	"strconv"
 	"time"
 )
 
 // SendResponse is the response from the Ntfy server after successfully sending a message.
+// The server echoes the published message back, in the same format as subscriptions receive it.
 type SendResponse struct {
-	ID      string   `json:"id"`      // :"bUhbhgmmbeW0"
-	Time    UnixTime `json:"time"`    // :1685150791
-	Expires UnixTime `json:"expires"` // :1685193991
-	Event   string   `json:"event"`   // :"message"
-	Topic   string   `json:"topic"`   // :"Server"
-	Message string   `json:"message"` // :"triggered"
+	MessageEvent
+
+	Attempts   int    `json:"-"` // Number of attempts it took to publish the message, including the successful one.
+	Reconciled bool   `json:"-"` // Whether the message was found on the server after an attempt failed; see IdempotencyOpts.
+	Server     string `json:"-"` // URL of the server which accepted the message.
 }
 
 // UnixTime allows unmarshalling a Unix timestamp from JSON into a time.Time.
@@ -34,3 +35,12 @@ func (u *UnixTime) UnmarshalJSON(b []byte) error {
 	}
 	return nil
 }
+
+// MarshalJSON marshals a time.Time into JSON as a Unix timestamp.
+// The zero time is marshalled as 0.
+func (u UnixTime) MarshalJSON() ([]byte, error) {
+	if u.IsZero() {
+		return []byte("0"), nil
+	}
+	return []byte(strconv.FormatInt(u.Unix(), 10)), nil
+}
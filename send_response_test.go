@@ -14,3 +14,15 @@ func TestUnixTime_UnmarshalJSON(t *testing.T) {
 	r.NoError(target.UnmarshalJSON([]byte("1685150791")))
 	r.Equal("2023-05-27T01:26:31Z", target.In(time.UTC).Format(time.RFC3339))
 }
+
+func TestUnixTime_MarshalJSON(t *testing.T) {
+	r := require.New(t)
+
+	buf, err := UnixTime{time.Unix(1685150791, 0)}.MarshalJSON()
+	r.NoError(err)
+	r.Equal("1685150791", string(buf))
+
+	buf, err = UnixTime{}.MarshalJSON()
+	r.NoError(err)
+	r.Equal("0", string(buf))
+}
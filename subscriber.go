@@ -0,0 +1,414 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStreamClosed is returned by Subscriber.Subscribe when the server ends the subscription stream
+// and reconnecting is disabled.
+var ErrStreamClosed = errors.New("subscription stream closed by server")
+
+// ErrKeepaliveTimeout is reported when a subscription receives no events within its keepalive timeout.
+var ErrKeepaliveTimeout = errors.New("no keepalive received from server")
+
+// DefaultKeepaliveTimeout is used when SubscriberOpts.KeepaliveTimeout is zero.
+// Ntfy sends keepalive events every 45 seconds by default.
+const DefaultKeepaliveTimeout = 90 * time.Second
+
+// Subscriber receives notification messages from a Ntfy server.
+type Subscriber interface {
+	// Subscribe opens a subscription to the given topic (or comma-separated list of topics)
+	// and calls handler for every event received, until ctx is cancelled.
+	// Dropped connections are re-established, resuming after the last message received, or from
+	// the last open or keepalive event if none was, unless reconnecting is disabled, in which case Subscribe returns once the stream ends.
+	// Client errors reported by the server, such as ErrUnauthorized, are returned without reconnecting.
+	// If a CursorStore is configured, the subscription starts after the last acknowledged message.
+	// Handlers are called sequentially from the calling goroutine.
+	Subscribe(ctx context.Context, topic string, handler EventHandler) error
+
+	// Ack records e as processed in the configured CursorStore, so that a subscription started
+	// later resumes after it. Until a message is acknowledged it will be delivered again after a
+	// restart, giving at-least-once delivery. Ack does nothing if no CursorStore is configured.
+	Ack(e *MessageEvent) error
+
+	// Poll returns the messages currently cached for the given topic, without holding a connection open.
+	// See: https://docs.ntfy.sh/subscribe/api/#poll-for-messages
+	Poll(ctx context.Context, topic string, opts PollOpts) ([]*MessageEvent, error)
+}
+
+// EventHandler is called by a Subscriber for each event it receives.
+type EventHandler func(e *MessageEvent)
+
+// ConnState describes the state of a subscription's connection to the server.
+type ConnState int8
+
+const (
+	StateConnecting   ConnState = iota // A connection is being opened.
+	StateConnected                     // The connection is open and events are being received.
+	StateDisconnected                  // The connection was lost; a reconnect will be attempted.
+	StateClosed                        // The subscription has ended.
+)
+
+func (c ConnState) String() string {
+	switch c {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateClosed:
+		return "closed"
+	default:
+		return fmt.Sprintf("ConnState(%d)", int8(c))
+	}
+}
+
+// Transport selects the protocol a Subscriber uses to receive events.
+// See: https://docs.ntfy.sh/subscribe/api/
+type Transport int8
+
+const (
+	TransportJSON      Transport = iota // Newline-delimited JSON stream from /<topic>/json (default).
+	TransportSSE                        // Server-Sent Events from /<topic>/sse.
+	TransportWebSocket                  // WebSocket connection to /<topic>/ws.
+)
+
+type subscriber struct {
+	server     url.URL
+	headers    http.Header
+	httpClient HttpClient
+	transport  Transport
+
+	pingInterval     time.Duration
+	keepaliveTimeout time.Duration
+	backoff          Backoff
+	noReconnect      bool
+	onStateChange    func(state ConnState, err error)
+	cursors          CursorStore
+}
+
+// SubscriberOpts contains the configuration options for a new Subscriber.
+type SubscriberOpts struct {
+	Server     *url.URL
+	Auth       Authorization
+	Headers    http.Header
+	HttpClient HttpClient
+	Transport  Transport
+
+	// PingInterval is how often a WebSocket subscriber sends pings to the server.
+	// Pings from the server are always answered; if zero, the client sends none of its own.
+	PingInterval time.Duration
+
+	// KeepaliveTimeout is how long a subscription may go without receiving any event, keepalives
+	// included, before it is considered dead and reconnected. If zero, DefaultKeepaliveTimeout
+	// is used; if negative, the connection is never timed out.
+	KeepaliveTimeout time.Duration
+
+	// Backoff controls the delay between reconnect attempts. If nil, DefaultBackoff is used.
+	// A retry hint sent by the server over SSE is used instead when it is longer.
+	Backoff *Backoff
+
+	// DisableReconnect makes Subscribe return as soon as the connection is lost.
+	DisableReconnect bool
+
+	// OnStateChange, if set, is called whenever the connection state changes.
+	// err describes why the connection was lost, for StateDisconnected and StateClosed.
+	OnStateChange func(state ConnState, err error)
+
+	// Cursors, if set, persists the last acknowledged message per topic; see Subscriber.Ack.
+	Cursors CursorStore
+}
+
+// NewSubscriber creates a subscriber for the given Ntfy server URL.
+func NewSubscriber(opts SubscriberOpts) Subscriber {
+	retv := subscriber{}
+
+	retv.server = serverOrDefault(opts.Server)
+
+	if opts.Headers == nil {
+		retv.headers = make(http.Header)
+	} else {
+		retv.headers = opts.Headers.Clone()
+	}
+
+	if opts.Auth != nil {
+		retv.headers.Set("Authorization", opts.Auth.Header())
+	}
+
+	retv.transport = opts.Transport
+	retv.pingInterval = opts.PingInterval
+
+	switch {
+	case opts.KeepaliveTimeout == 0:
+		retv.keepaliveTimeout = DefaultKeepaliveTimeout
+	case opts.KeepaliveTimeout > 0:
+		retv.keepaliveTimeout = opts.KeepaliveTimeout
+	}
+
+	if opts.Backoff == nil {
+		retv.backoff = DefaultBackoff
+	} else {
+		retv.backoff = *opts.Backoff
+	}
+	retv.noReconnect = opts.DisableReconnect
+	retv.onStateChange = opts.OnStateChange
+	retv.cursors = opts.Cursors
+
+	if opts.HttpClient != nil {
+		retv.httpClient = opts.HttpClient
+	} else if retv.transport == TransportWebSocket {
+		retv.httpClient = webSocketHttpClient()
+	} else {
+		retv.httpClient = http.DefaultClient
+	}
+
+	return &retv
+}
+
+// Subscribe opens a subscription to the given topic and calls handler for every event received.
+func (s *subscriber) Subscribe(ctx context.Context, topic string, handler EventHandler) error {
+	if topic == "" {
+		return errors.New("topic must not be empty")
+	}
+	if handler == nil {
+		return errors.New("handler must not be nil")
+	}
+
+	since, err := s.initialSince(topic)
+	if err != nil {
+		return err
+	}
+
+	var (
+		lastID   string
+		lastSeen time.Time
+		attempt  int
+	)
+	for {
+		s.setState(StateConnecting, nil)
+
+		// resume after the last message received or, before the first, from when the previous
+		// connection was last known to be up, so that nothing published in between is missed
+		query := url.Values{}
+		switch {
+		case lastID != "":
+			query.Set("since", lastID)
+		case since != "":
+			query.Set("since", string(since))
+		case !lastSeen.IsZero():
+			query.Set("since", string(SinceTime(lastSeen)))
+		}
+
+		var retryHint time.Duration
+		stream, err := s.open(ctx, topic, query)
+		if err == nil {
+			attempt = 0
+			s.setState(StateConnected, nil)
+			err = s.consume(stream, handler, &lastID, &lastSeen)
+			if h, ok := stream.(interface{ RetryHint() time.Duration }); ok {
+				retryHint = h.RetryHint()
+			}
+			_ = stream.Close()
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			s.setState(StateClosed, ctxErr)
+			return ctxErr
+		}
+
+		if s.noReconnect || isPermanent(err) {
+			s.setState(StateClosed, err)
+			return err
+		}
+		s.setState(StateDisconnected, err)
+
+		delay := s.backoff.Delay(attempt)
+		if retryHint > delay {
+			delay = retryHint
+		}
+		attempt++
+
+		if err := sleepContext(ctx, delay); err != nil {
+			s.setState(StateClosed, err)
+			return err
+		}
+	}
+}
+
+// consume reads events from stream and passes them to handler until the stream fails,
+// recording the ID of each message received in lastID, and the time of each open or
+// keepalive event in lastSeen. Time spent in handler does not count towards the keepalive timeout.
+func (s *subscriber) consume(stream eventStream, handler EventHandler, lastID *string, lastSeen *time.Time) error {
+	var (
+		timedOut atomic.Bool
+		watchdog *time.Timer
+	)
+	if s.keepaliveTimeout > 0 {
+		watchdog = time.AfterFunc(s.keepaliveTimeout, func() {
+			timedOut.Store(true)
+			_ = stream.Close()
+		})
+		defer watchdog.Stop()
+	}
+
+	for {
+		e, err := stream.Next()
+		if err != nil {
+			if timedOut.Load() {
+				return ErrKeepaliveTimeout
+			}
+			if errors.Is(err, io.EOF) {
+				return ErrStreamClosed
+			}
+			return fmt.Errorf("failed to read event: %w", err)
+		}
+
+		if watchdog != nil && !watchdog.Stop() {
+			return ErrKeepaliveTimeout // fired while the event was being decoded
+		}
+
+		switch {
+		case e.IsMessage() && e.ID != "":
+			*lastID = e.ID
+		case e.Event == EventOpen || e.Event == EventKeepalive:
+			*lastSeen = e.Time.Time
+			if lastSeen.IsZero() {
+				*lastSeen = time.Now()
+			}
+		}
+		handler(e)
+
+		if watchdog != nil {
+			watchdog.Reset(s.keepaliveTimeout)
+		}
+	}
+}
+
+// Ack records e as processed in the configured CursorStore.
+func (s *subscriber) Ack(e *MessageEvent) error {
+	if s.cursors == nil || e == nil || !e.IsMessage() {
+		return nil
+	}
+
+	prev, err := s.cursors.Load(e.Topic)
+	if err != nil {
+		return fmt.Errorf("failed to load cursor: %w", err)
+	}
+	if prev != nil && prev.Time.After(e.Time.Time) {
+		return nil // never move a cursor backwards
+	}
+
+	return s.cursors.Save(e.Topic, Cursor{ID: e.ID, Time: e.Time.Time})
+}
+
+// initialSince returns the since parameter for resuming the given topics from the configured
+// CursorStore. A single topic resumes after its last message ID. Since message IDs are specific
+// to a topic, multiple topics resume from the time of the oldest of their cursors.
+func (s *subscriber) initialSince(topic string) (Since, error) {
+	if s.cursors == nil {
+		return "", nil
+	}
+
+	topics := strings.Split(topic, ",")
+	var oldest *Cursor
+	for _, t := range topics {
+		c, err := s.cursors.Load(t)
+		if err != nil {
+			return "", fmt.Errorf("failed to load cursor: %w", err)
+		}
+		if c == nil {
+			continue
+		}
+		if len(topics) == 1 && c.ID != "" {
+			return SinceID(c.ID), nil
+		}
+		if oldest == nil || c.Time.Before(oldest.Time) {
+			oldest = c
+		}
+	}
+
+	if oldest == nil || oldest.Time.IsZero() {
+		return "", nil
+	}
+	return SinceTime(oldest.Time), nil
+}
+
+func (s *subscriber) setState(state ConnState, err error) {
+	if s.onStateChange != nil {
+		s.onStateChange(state, err)
+	}
+}
+
+// eventStream is a source of events read from a single connection to the server.
+type eventStream interface {
+	// Next blocks until the next event is available.
+	// It returns io.EOF once the server has closed the stream.
+	Next() (*MessageEvent, error)
+	Close() error
+}
+
+// open connects to the server and returns a stream of events for the given topic.
+// query is added to the endpoint URL, e.g. to resume from a given message.
+func (s *subscriber) open(ctx context.Context, topic string, query url.Values) (eventStream, error) {
+	var endpoint *url.URL
+	switch s.transport {
+	case TransportJSON:
+		endpoint = s.server.JoinPath(topic, "json")
+	case TransportSSE:
+		endpoint = s.server.JoinPath(topic, "sse")
+	case TransportWebSocket:
+		endpoint = s.server.JoinPath(topic, "ws")
+	default:
+		return nil, fmt.Errorf("unsupported transport: %d", s.transport)
+	}
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header = s.headers.Clone()
+
+	var wsKey string
+	switch s.transport {
+	case TransportSSE:
+		req.Header.Set("Accept", "text/event-stream")
+	case TransportWebSocket:
+		if wsKey, err = setWebSocketHeaders(req.Header); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	if s.transport == TransportWebSocket {
+		return newWebSocketStream(ctx, resp, wsKey, s.pingInterval)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := newAPIError(resp)
+		closeBody(resp)
+		return nil, fmt.Errorf("failed to subscribe: %w", apiErr)
+	}
+
+	if resp.Body == nil {
+		return nil, fmt.Errorf("response body is nil")
+	}
+
+	if s.transport == TransportSSE {
+		return newSSEStream(resp.Body), nil
+	}
+	return newJSONStream(resp.Body), nil
+}
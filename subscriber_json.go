@@ -0,0 +1,53 @@
+package gotfy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// maxEventSize bounds the size of a single event read from a subscription stream.
+const maxEventSize = 1 << 20
+
+// jsonStream reads newline-delimited JSON events, as served by the /<topic>/json endpoint.
+// See: https://docs.ntfy.sh/subscribe/api/#subscribe-as-json-stream
+type jsonStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+func newJSONStream(body io.ReadCloser) *jsonStream {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), maxEventSize)
+
+	return &jsonStream{
+		body:    body,
+		scanner: scanner,
+	}
+}
+
+func (j *jsonStream) Next() (*MessageEvent, error) {
+	for j.scanner.Scan() {
+		line := bytes.TrimSpace(j.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var e MessageEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event from JSON: %w", err)
+		}
+		return &e, nil
+	}
+
+	if err := j.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (j *jsonStream) Close() error {
+	return j.body.Close()
+}
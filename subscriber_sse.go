@@ -0,0 +1,126 @@
+package gotfy
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sseStream reads Server-Sent Events, as served by the /<topic>/sse endpoint.
+// See: https://docs.ntfy.sh/subscribe/api/#subscribe-as-sse-stream
+// See: https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+type sseStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+
+	lastID string
+	retry  time.Duration
+}
+
+func newSSEStream(body io.ReadCloser) *sseStream {
+	// lines are bounded as they are read, so that a line without an end can't exhaust memory
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), maxEventSize)
+
+	return &sseStream{
+		body:    body,
+		scanner: scanner,
+	}
+}
+
+func (s *sseStream) Next() (*MessageEvent, error) {
+	var (
+		eventName string
+		data      strings.Builder
+		hasData   bool
+		size      int
+	)
+
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line == "" {
+			if hasData {
+				return s.dispatch(eventName, data.String())
+			}
+			// a frame without data is ignored, and the size of the next one counted afresh
+			eventName = ""
+			size = 0
+			continue
+		}
+
+		size += len(line) + 1
+		if size > maxEventSize {
+			return nil, fmt.Errorf("event exceeds %d bytes", maxEventSize)
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue // comment
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			eventName = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseInt(value, 10, 64); err == nil && ms >= 0 {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	// an incomplete event at the end of the stream is discarded, per the spec
+	if err := s.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("event exceeds %d bytes", maxEventSize)
+		}
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// dispatch decodes the data of a complete SSE frame into a MessageEvent.
+func (s *sseStream) dispatch(eventName, data string) (*MessageEvent, error) {
+	var e MessageEvent
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event from JSON: %w", err)
+	}
+
+	if e.Event == "" {
+		if eventName == "" {
+			e.Event = EventMessage
+		} else {
+			e.Event = EventType(eventName)
+		}
+	}
+	if e.ID == "" {
+		e.ID = s.lastID
+	}
+
+	return &e, nil
+}
+
+// RetryHint returns the reconnection delay most recently requested by the server, or 0 if none was sent.
+func (s *sseStream) RetryHint() time.Duration {
+	return s.retry
+}
+
+func (s *sseStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,111 @@
+package gotfy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SSEStream_Frames(t *testing.T) {
+	r := require.New(t)
+
+	body := strings.Join([]string{
+		": comment",
+		"retry: 2500",
+		"event: open",
+		`data: {"id":"a1","time":1685150791,"event":"open","topic":"mytopic"}`,
+		"",
+		"",
+		"id: a2",
+		`data: {"time":1685150792,"topic":"mytopic",`,
+		`data: "message":"hi"}`,
+		"",
+		"event: keepalive",
+		`data: {"id":"a3","time":1685150793,"topic":"mytopic"}`,
+		"",
+		`data: {"id":"a4","time":1685150794`,
+	}, "\r\n")
+	sut := newSSEStream(io.NopCloser(strings.NewReader(body)))
+
+	e, err := sut.Next()
+	r.NoError(err)
+	r.Equal(EventOpen, e.Event)
+	r.Equal("a1", e.ID)
+	r.Equal(2500*time.Millisecond, sut.RetryHint())
+
+	e, err = sut.Next()
+	r.NoError(err)
+	r.Equal(EventMessage, e.Event)
+	r.Equal("a2", e.ID)
+	r.Equal("hi", e.Message)
+
+	e, err = sut.Next()
+	r.NoError(err)
+	r.Equal(EventKeepalive, e.Event)
+	r.Equal("a3", e.ID)
+
+	_, err = sut.Next()
+	r.ErrorIs(err, io.EOF)
+}
+
+func Test_SSEStream_SizeLimit(t *testing.T) {
+	r := require.New(t)
+
+	// frames without data only count towards their own size
+	comment := ": " + strings.Repeat("x", maxEventSize/2) + "\n"
+	body := strings.Repeat(comment+"\n", 4) + `data: {"id":"a1","time":1685150791,"topic":"mytopic"}` + "\n\n"
+	sut := newSSEStream(io.NopCloser(strings.NewReader(body)))
+	e, err := sut.Next()
+	r.NoError(err)
+	r.Equal("a1", e.ID)
+
+	// a line which never ends fails once it's too long, rather than being read to the end
+	sut = newSSEStream(io.NopCloser(io.MultiReader(strings.NewReader("data: "), endlessReader('x'))))
+	_, err = sut.Next()
+	r.EqualError(err, fmt.Sprintf("event exceeds %d bytes", maxEventSize))
+
+	sut = newSSEStream(io.NopCloser(strings.NewReader(strings.Repeat(comment, 3) + "\n")))
+	_, err = sut.Next()
+	r.EqualError(err, fmt.Sprintf("event exceeds %d bytes", maxEventSize))
+}
+
+// endlessReader reads as the same byte, forever.
+type endlessReader byte
+
+func (b endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(b)
+	}
+	return len(p), nil
+}
+
+func Test_Subscriber_SSE(t *testing.T) {
+	r := require.New(t)
+
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/mytopic/sse", req.URL.Path)
+		assert.Equal(t, "text/event-stream", req.Header.Get("Accept"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "event: open\ndata: {\"id\":\"a1\",\"time\":1685150791,\"event\":\"open\",\"topic\":\"mytopic\"}\n\n")
+		_, _ = fmt.Fprint(w, "id: a2\ndata: {\"id\":\"a2\",\"time\":1685150792,\"event\":\"message\",\"topic\":\"mytopic\",\"message\":\"hi\"}\n\n")
+	}, SubscriberOpts{Transport: TransportSSE, DisableReconnect: true})
+
+	var events []*MessageEvent
+	err := sut.Subscribe(context.Background(), "mytopic", func(e *MessageEvent) {
+		events = append(events, e)
+	})
+	r.ErrorIs(err, ErrStreamClosed)
+
+	r.Len(events, 2)
+	r.Equal(EventOpen, events[0].Event)
+	r.Equal(EventMessage, events[1].Event)
+	r.Equal("hi", events[1].Message)
+}
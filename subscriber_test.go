@@ -0,0 +1,188 @@
+package gotfy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSubscriber(t *testing.T, handler http.HandlerFunc, opts SubscriberOpts) Subscriber {
+	opts.Server = newTestServer(t, handler)
+	return NewSubscriber(opts)
+}
+
+func Test_Subscriber_JSONStream(t *testing.T) {
+	r := require.New(t)
+
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/mytopic/json", req.URL.Path)
+		assert.Equal(t, "Bearer tk_0123456789", req.Header.Get("Authorization"))
+
+		_, _ = fmt.Fprintln(w, `{"id":"a1","time":1685150791,"event":"open","topic":"mytopic"}`)
+		_, _ = fmt.Fprintln(w, `{"id":"a2","time":1685150792,"event":"keepalive","topic":"mytopic"}`)
+		_, _ = fmt.Fprintln(w, ``)
+		_, _ = fmt.Fprintln(w, `{"id":"a3","time":1685150793,"expires":1685193993,"event":"message","topic":"mytopic","message":"hi","title":"t","tags":["tag"],"priority":4,"click":"https://example.com"}`)
+		_, _ = fmt.Fprintln(w, `{"id":"a4","time":1685150794,"event":"poll_request","topic":"mytopic","message":"New message","poll_id":"a3"}`)
+	}, SubscriberOpts{Auth: AccessToken("tk_0123456789"), DisableReconnect: true})
+
+	var events []*MessageEvent
+	err := sut.Subscribe(context.Background(), "mytopic", func(e *MessageEvent) {
+		events = append(events, e)
+	})
+	r.ErrorIs(err, ErrStreamClosed)
+
+	r.Len(events, 4)
+	r.Equal(EventOpen, events[0].Event)
+	r.Equal(EventKeepalive, events[1].Event)
+	r.True(events[2].IsMessage())
+	r.Equal("a3", events[2].ID)
+	r.Equal("hi", events[2].Message)
+	r.Equal("t", events[2].Title)
+	r.Equal([]string{"tag"}, events[2].Tags)
+	r.Equal(PriorityHigh, events[2].Priority)
+	r.Equal("https://example.com", events[2].Click)
+	r.Equal(int64(1685193993), events[2].Expires.Unix())
+	r.Equal(EventPollRequest, events[3].Event)
+	r.Equal("a3", events[3].PollID)
+}
+
+func Test_Subscriber_StopsOnContextCancel(t *testing.T) {
+	r := require.New(t)
+
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		_, _ = fmt.Fprintln(w, `{"id":"a1","time":1685150791,"event":"open","topic":"mytopic"}`)
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}, SubscriberOpts{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := sut.Subscribe(ctx, "mytopic", func(e *MessageEvent) {
+		cancel()
+	})
+	r.True(errors.Is(err, context.Canceled), "unexpected error: %v", err)
+}
+
+func Test_Subscriber_HTTPError(t *testing.T) {
+	r := require.New(t)
+
+	connections := 0
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		connections++
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprintln(w, `{"code":40301,"http":403,"error":"forbidden","link":"https://ntfy.sh/docs/publish/#authentication"}`)
+	}, SubscriberOpts{})
+
+	// client errors are not retried
+	err := sut.Subscribe(context.Background(), "mytopic", func(e *MessageEvent) {})
+	r.ErrorIs(err, ErrForbidden)
+	r.EqualError(err, "failed to subscribe: HTTP 403: forbidden (ntfy error 40301)")
+	r.Equal(1, connections)
+}
+
+func Test_Subscriber_ReconnectsSinceLastMessage(t *testing.T) {
+	r := require.New(t)
+
+	var (
+		mu          sync.Mutex
+		connections []string
+	)
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		connections = append(connections, req.URL.Query().Get("since"))
+		n := len(connections)
+		mu.Unlock()
+
+		_, _ = fmt.Fprintln(w, `{"id":"o1","time":1685150791,"event":"open","topic":"mytopic"}`)
+		_, _ = fmt.Fprintf(w, `{"id":"m%d","time":1685150792,"event":"message","topic":"mytopic","message":"hi"}`+"\n", n)
+	}, SubscriberOpts{
+		Backoff: &Backoff{Initial: time.Millisecond},
+	})
+
+	var received []string
+	ctx, cancel := context.WithCancel(context.Background())
+	err := sut.Subscribe(ctx, "mytopic", func(e *MessageEvent) {
+		if e.IsMessage() {
+			received = append(received, e.ID)
+		}
+		if len(received) == 3 {
+			cancel()
+		}
+	})
+	r.ErrorIs(err, context.Canceled)
+
+	r.Equal([]string{"m1", "m2", "m3"}, received)
+	mu.Lock()
+	defer mu.Unlock()
+	r.Equal([]string{"", "m1", "m2"}, connections)
+}
+
+func Test_Subscriber_ReconnectsSinceLastKeepalive(t *testing.T) {
+	r := require.New(t)
+
+	var (
+		mu          sync.Mutex
+		connections []string
+	)
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		connections = append(connections, req.URL.Query().Get("since"))
+		n := len(connections)
+		mu.Unlock()
+
+		// the first connection drops before any message arrives
+		_, _ = fmt.Fprintln(w, `{"id":"o1","time":1685150791,"event":"open","topic":"mytopic"}`)
+		if n == 1 {
+			_, _ = fmt.Fprintln(w, `{"id":"k1","time":1685150821,"event":"keepalive","topic":"mytopic"}`)
+			return
+		}
+		_, _ = fmt.Fprintln(w, `{"id":"m1","time":1685150830,"event":"message","topic":"mytopic","message":"hi"}`)
+	}, SubscriberOpts{
+		Backoff: &Backoff{Initial: time.Millisecond},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := sut.Subscribe(ctx, "mytopic", func(e *MessageEvent) {
+		if e.IsMessage() {
+			cancel()
+		}
+	})
+	r.ErrorIs(err, context.Canceled)
+
+	mu.Lock()
+	defer mu.Unlock()
+	r.Equal([]string{"", "1685150821"}, connections)
+}
+
+func Test_Subscriber_KeepaliveWatchdog(t *testing.T) {
+	r := require.New(t)
+
+	var states []ConnState
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		_, _ = fmt.Fprintln(w, `{"id":"o1","time":1685150791,"event":"open","topic":"mytopic"}`)
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}, SubscriberOpts{
+		KeepaliveTimeout: 50 * time.Millisecond,
+		Backoff:          &Backoff{Initial: time.Millisecond},
+		OnStateChange: func(state ConnState, err error) {
+			states = append(states, state)
+			if state == StateDisconnected {
+				assert.ErrorIs(t, err, ErrKeepaliveTimeout)
+				cancel()
+			}
+		},
+	})
+
+	err := sut.Subscribe(ctx, "mytopic", func(e *MessageEvent) {})
+	r.ErrorIs(err, context.Canceled)
+	r.Equal([]ConnState{StateConnecting, StateConnected, StateDisconnected, StateClosed}, states)
+}
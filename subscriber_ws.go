@@ -0,0 +1,382 @@
+package gotfy
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the handshake key to compute Sec-WebSocket-Accept.
+// See: https://datatracker.ietf.org/doc/html/rfc6455#section-1.3
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsCloseTimeout bounds how long closing a WebSocket waits for the server to acknowledge the close.
+const wsCloseTimeout = 5 * time.Second
+
+// WebSocket opcodes and close codes.
+// See: https://datatracker.ietf.org/doc/html/rfc6455#section-5.2
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+
+	wsCloseNormal     = 1000
+	wsCloseNoStatus   = 1005
+	wsCloseTooBig     = 1009
+	wsCloseProtoError = 1002
+)
+
+// webSocketHttpClient returns the HTTP client used for WebSocket handshakes when none is configured.
+// Upgrading a connection requires HTTP/1.1, so HTTP/2 is disabled.
+func webSocketHttpClient() HttpClient {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.ForceAttemptHTTP2 = false
+	t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	if t.TLSClientConfig != nil {
+		t.TLSClientConfig.NextProtos = nil
+	}
+	return &http.Client{Transport: t}
+}
+
+// setWebSocketHeaders adds the opening handshake headers to h and returns the generated key.
+// See: https://datatracker.ietf.org/doc/html/rfc6455#section-4.1
+func setWebSocketHeaders(h http.Header) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate WebSocket key: %w", err)
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	h.Set("Connection", "Upgrade")
+	h.Set("Upgrade", "websocket")
+	h.Set("Sec-WebSocket-Version", "13")
+	h.Set("Sec-WebSocket-Key", key)
+	return key, nil
+}
+
+// webSocketAccept computes the Sec-WebSocket-Accept value the server must send for the given key.
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// wsStream reads JSON events from a WebSocket, as served by the /<topic>/ws endpoint.
+// See: https://docs.ntfy.sh/subscribe/api/#websockets
+type wsStream struct {
+	conn   io.ReadWriteCloser
+	reader *bufio.Reader
+
+	readMu    sync.Mutex
+	writeMu   sync.Mutex
+	closeSent bool // guarded by writeMu
+
+	closeReceived     chan struct{}
+	closeReceivedOnce sync.Once
+
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// newWebSocketStream completes the opening handshake from the server's response and returns the
+// resulting stream. The stream is closed when ctx is cancelled.
+func newWebSocketStream(ctx context.Context, resp *http.Response, key string, pingInterval time.Duration) (eventStream, error) {
+	fail := func(err error) (eventStream, error) {
+		closeBody(resp)
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return fail(fmt.Errorf("failed to subscribe: %w", newAPIError(resp)))
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		return fail(errors.New("failed to subscribe: server did not upgrade to WebSocket"))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		return fail(errors.New("failed to subscribe: invalid Sec-WebSocket-Accept header"))
+	}
+
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return fail(errors.New("failed to subscribe: HTTP client does not support connection upgrades"))
+	}
+
+	s := &wsStream{
+		conn:          conn,
+		reader:        bufio.NewReader(conn),
+		closeReceived: make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = s.Close()
+		case <-s.done:
+		}
+	}()
+
+	if pingInterval > 0 {
+		go s.ping(pingInterval)
+	}
+
+	return s, nil
+}
+
+func (s *wsStream) Next() (*MessageEvent, error) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+
+	var msg []byte
+	for {
+		f, err := readWSFrame(s.reader)
+		if err != nil {
+			if errors.Is(err, errWSFrameTooLarge) {
+				_ = s.writeClose(wsCloseTooBig)
+			} else if errors.Is(err, errWSProtocol) {
+				_ = s.writeClose(wsCloseProtoError)
+			}
+			return nil, err
+		}
+
+		switch f.opcode {
+		case wsOpPing:
+			if err := s.writeFrame(wsOpPong, f.payload); err != nil {
+				return nil, err
+			}
+		case wsOpPong:
+			// nothing to do; receiving it is enough to keep the connection alive
+		case wsOpClose:
+			s.closeReceivedOnce.Do(func() { close(s.closeReceived) })
+			code := wsCloseNoStatus
+			if len(f.payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(f.payload))
+			}
+			_ = s.writeClose(code)
+			if code == wsCloseNormal || code == wsCloseNoStatus {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("WebSocket closed by server: %d %s", code, f.payload[2:])
+		case wsOpText, wsOpBinary, wsOpContinuation:
+			if len(msg)+len(f.payload) > maxEventSize {
+				_ = s.writeClose(wsCloseTooBig)
+				return nil, errWSFrameTooLarge
+			}
+			msg = append(msg, f.payload...)
+			if !f.fin {
+				continue
+			}
+
+			var e MessageEvent
+			if err := json.Unmarshal(msg, &e); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal event from JSON: %w", err)
+			}
+			return &e, nil
+		default:
+			_ = s.writeClose(wsCloseProtoError)
+			return nil, fmt.Errorf("%w: unknown opcode %d", errWSProtocol, f.opcode)
+		}
+	}
+}
+
+// Close performs the closing handshake and closes the underlying connection.
+// See: https://datatracker.ietf.org/doc/html/rfc6455#section-7
+func (s *wsStream) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		_ = s.writeClose(wsCloseNormal)
+
+		select {
+		case <-s.closeReceived:
+		default:
+			if s.readMu.TryLock() {
+				// nobody is reading, so wait for the server's close frame here
+				timer := time.AfterFunc(wsCloseTimeout, func() { _ = s.conn.Close() })
+				for {
+					f, err := readWSFrame(s.reader)
+					if err != nil || f.opcode == wsOpClose {
+						break
+					}
+				}
+				timer.Stop()
+				s.readMu.Unlock()
+			} else {
+				// Next will see the server's close frame
+				timer := time.NewTimer(wsCloseTimeout)
+				select {
+				case <-s.closeReceived:
+				case <-timer.C:
+				}
+				timer.Stop()
+			}
+		}
+
+		s.closeErr = s.conn.Close()
+	})
+	return s.closeErr
+}
+
+func (s *wsStream) ping(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.writeFrame(wsOpPing, nil); err != nil {
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// writeClose sends a close frame with the given status code, unless one has already been sent.
+func (s *wsStream) writeClose(code int) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.closeSent {
+		return nil
+	}
+	s.closeSent = true
+
+	var payload []byte
+	if code != wsCloseNoStatus {
+		payload = make([]byte, 2)
+		binary.BigEndian.PutUint16(payload, uint16(code))
+	}
+	return writeWSFrame(s.conn, wsOpClose, payload, true)
+}
+
+func (s *wsStream) writeFrame(opcode byte, payload []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.closeSent {
+		return nil // no data frames may follow a close frame
+	}
+	return writeWSFrame(s.conn, opcode, payload, true)
+}
+
+var (
+	errWSProtocol      = errors.New("WebSocket protocol error")
+	errWSFrameTooLarge = fmt.Errorf("WebSocket message exceeds %d bytes", maxEventSize)
+)
+
+type wsFrame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// readWSFrame reads a single frame, unmasking its payload if needed.
+// See: https://datatracker.ietf.org/doc/html/rfc6455#section-5.2
+func readWSFrame(r io.Reader) (wsFrame, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(r, hdr[:2]); err != nil {
+		return wsFrame{}, err
+	}
+
+	f := wsFrame{
+		fin:    hdr[0]&0x80 != 0,
+		opcode: hdr[0] & 0x0f,
+	}
+	if hdr[0]&0x70 != 0 {
+		return wsFrame{}, fmt.Errorf("%w: reserved bits set", errWSProtocol)
+	}
+
+	masked := hdr[1]&0x80 != 0
+	length := uint64(hdr[1] & 0x7f)
+	switch length {
+	case 126:
+		if _, err := io.ReadFull(r, hdr[:2]); err != nil {
+			return wsFrame{}, err
+		}
+		length = uint64(binary.BigEndian.Uint16(hdr[:2]))
+	case 127:
+		if _, err := io.ReadFull(r, hdr[:8]); err != nil {
+			return wsFrame{}, err
+		}
+		length = binary.BigEndian.Uint64(hdr[:8])
+	}
+	if length > maxEventSize {
+		return wsFrame{}, errWSFrameTooLarge
+	}
+	if f.opcode >= wsOpClose && (length > 125 || !f.fin) {
+		return wsFrame{}, fmt.Errorf("%w: invalid control frame", errWSProtocol)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return wsFrame{}, err
+		}
+	}
+
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return wsFrame{}, err
+	}
+	if masked {
+		for i := range f.payload {
+			f.payload[i] ^= mask[i%4]
+		}
+	}
+
+	return f, nil
+}
+
+// writeWSFrame writes payload as a single, final frame. Frames sent by clients must be masked.
+func writeWSFrame(w io.Writer, opcode byte, payload []byte, mask bool) error {
+	buf := make([]byte, 0, 14+len(payload))
+	buf = append(buf, 0x80|opcode)
+
+	var maskBit byte
+	if mask {
+		maskBit = 0x80
+	}
+
+	switch n := len(payload); {
+	case n < 126:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(buf[len(buf)-2:], uint16(n))
+	default:
+		buf = append(buf, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[len(buf)-8:], uint64(n))
+	}
+
+	if !mask {
+		buf = append(buf, payload...)
+	} else {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return fmt.Errorf("failed to generate WebSocket mask: %w", err)
+		}
+		buf = append(buf, key[:]...)
+		for i, b := range payload {
+			buf = append(buf, b^key[i%4])
+		}
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
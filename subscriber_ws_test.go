@@ -0,0 +1,149 @@
+package gotfy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acceptWebSocket performs the server side of the opening handshake.
+func acceptWebSocket(t *testing.T, w http.ResponseWriter, req *http.Request) (net.Conn, *bufio.ReadWriter) {
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	require.NoError(t, err)
+
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + webSocketAccept(req.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	require.NoError(t, rw.Flush())
+	return conn, rw
+}
+
+func Test_WSFrame_RoundTrip(t *testing.T) {
+	r := require.New(t)
+
+	for _, size := range []int{0, 1, 125, 126, 0xffff, 0x10000} {
+		payload := bytes.Repeat([]byte{'x'}, size)
+		for _, mask := range []bool{true, false} {
+			var buf bytes.Buffer
+			r.NoError(writeWSFrame(&buf, wsOpText, payload, mask))
+
+			f, err := readWSFrame(&buf)
+			r.NoError(err)
+			r.True(f.fin)
+			r.Equal(byte(wsOpText), f.opcode)
+			r.Equal(payload, f.payload)
+		}
+	}
+}
+
+func Test_Subscriber_WebSocket(t *testing.T) {
+	r := require.New(t)
+
+	serverDone := make(chan struct{})
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		defer close(serverDone)
+
+		assert.Equal(t, "/mytopic/ws", req.URL.Path)
+		assert.Equal(t, "Bearer tk_0123456789", req.Header.Get("Authorization"))
+		assert.Equal(t, "websocket", req.Header.Get("Upgrade"))
+
+		conn, rw := acceptWebSocket(t, w, req)
+		defer conn.Close()
+
+		_ = writeWSFrame(rw, wsOpText, []byte(`{"id":"a1","time":1685150791,"event":"open","topic":"mytopic"}`), false)
+		_ = writeWSFrame(rw, wsOpPing, []byte("hello"), false)
+		_ = rw.Flush()
+
+		f, err := readWSFrame(rw)
+		if assert.NoError(t, err) {
+			assert.Equal(t, byte(wsOpPong), f.opcode)
+			assert.Equal(t, []byte("hello"), f.payload)
+		}
+
+		// a fragmented message
+		_, _ = rw.Write([]byte{wsOpText, 20})
+		_, _ = rw.WriteString(`{"id":"a2","time":16`)
+		msg := `85150792,"event":"message","topic":"mytopic","message":"hi"}`
+		_, _ = rw.Write([]byte{0x80 | wsOpContinuation, byte(len(msg))})
+		_, _ = rw.WriteString(msg)
+
+		closePayload := make([]byte, 2)
+		binary.BigEndian.PutUint16(closePayload, wsCloseNormal)
+		_ = writeWSFrame(rw, wsOpClose, closePayload, false)
+		_ = rw.Flush()
+
+		f, err = readWSFrame(rw)
+		if assert.NoError(t, err) {
+			assert.Equal(t, byte(wsOpClose), f.opcode)
+			assert.Equal(t, closePayload, f.payload)
+		}
+	}, SubscriberOpts{
+		Transport:        TransportWebSocket,
+		Auth:             AccessToken("tk_0123456789"),
+		DisableReconnect: true,
+	})
+
+	var events []*MessageEvent
+	err := sut.Subscribe(context.Background(), "mytopic", func(e *MessageEvent) {
+		events = append(events, e)
+	})
+	r.ErrorIs(err, ErrStreamClosed)
+	<-serverDone
+
+	r.Len(events, 2)
+	r.Equal(EventOpen, events[0].Event)
+	r.Equal(EventMessage, events[1].Event)
+	r.Equal("hi", events[1].Message)
+}
+
+func Test_Subscriber_WebSocketClosesOnCancel(t *testing.T) {
+	r := require.New(t)
+
+	serverDone := make(chan struct{})
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		defer close(serverDone)
+
+		conn, rw := acceptWebSocket(t, w, req)
+		defer conn.Close()
+
+		_ = writeWSFrame(rw, wsOpText, []byte(`{"id":"a1","time":1685150791,"event":"open","topic":"mytopic"}`), false)
+		_ = rw.Flush()
+
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		f, err := readWSFrame(rw)
+		if assert.NoError(t, err) {
+			assert.Equal(t, byte(wsOpClose), f.opcode)
+			assert.Equal(t, uint16(wsCloseNormal), binary.BigEndian.Uint16(f.payload))
+		}
+		_ = writeWSFrame(rw, wsOpClose, f.payload, false)
+		_ = rw.Flush()
+	}, SubscriberOpts{Transport: TransportWebSocket})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := sut.Subscribe(ctx, "mytopic", func(e *MessageEvent) {
+		cancel()
+	})
+	r.True(errors.Is(err, context.Canceled), "unexpected error: %v", err)
+	<-serverDone
+}
+
+func Test_Subscriber_WebSocketRejected(t *testing.T) {
+	r := require.New(t)
+
+	sut := newTestSubscriber(t, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}, SubscriberOpts{Transport: TransportWebSocket, DisableReconnect: true})
+
+	err := sut.Subscribe(context.Background(), "mytopic", func(e *MessageEvent) {})
+	r.EqualError(err, "failed to subscribe: HTTP 401: Unauthorized")
+}
@@ -0,0 +1,142 @@
+package gotfy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// WireFormat selects how a Publisher encodes messages on the wire.
+type WireFormat int
+
+const (
+	// WireJSON posts messages as JSON documents to the server's root URL.
+	// See: https://docs.ntfy.sh/publish/#publish-as-json
+	WireJSON WireFormat = iota
+
+	// WireHeaders posts the message body as-is to the topic's URL, with every other field in
+	// an X- header. Action buttons are written in the simple format, which can't represent every
+	// value (see FormatSimpleActions), and header values which aren't printable ASCII are encoded
+	// as RFC 2047 encoded-words.
+	// See: https://docs.ntfy.sh/publish/#list-of-all-parameters
+	WireHeaders
+)
+
+// newRequest builds the HTTP request which publishes m, in the publisher's wire format.
+// All sends go through here, so that messages always reach the server in the format
+// defined by Message.MarshalJSON or messageHeaders.
+func (p *publisher) newRequest(ctx context.Context, m *Message) (*http.Request, error) {
+	if p.wire == WireHeaders {
+		return p.newHeaderRequest(ctx, m)
+	}
+
+	buf, err := m.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message to JSON: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.server.String(), bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header = p.headers.Clone()
+
+	return req, nil
+}
+
+// newHeaderRequest builds the HTTP request which publishes m in the WireHeaders format.
+func (p *publisher) newHeaderRequest(ctx context.Context, m *Message) (*http.Request, error) {
+	if m.Topic == "" {
+		return nil, errors.New("topic must not be empty")
+	}
+
+	h, err := messageHeaders(m)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := p.server.JoinPath(m.Topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), strings.NewReader(m.Message))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header = p.headers.Clone()
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	for name, values := range h {
+		req.Header[name] = values
+	}
+
+	return req, nil
+}
+
+// messageHeaders encodes the fields of m, other than its topic and message, as the X- headers
+// accepted by Ntfy. Tags are sent as a comma-separated list, so they can't contain commas.
+// Values which aren't printable ASCII are encoded as RFC 2047 encoded-words, which Ntfy decodes.
+// See: https://docs.ntfy.sh/publish/#list-of-all-parameters
+func messageHeaders(m *Message) (http.Header, error) {
+	h := make(http.Header)
+	set := func(name, value string) {
+		if value != "" {
+			h.Set(name, headerValue(value))
+		}
+	}
+
+	for _, tag := range m.Tags {
+		if strings.Contains(tag, ",") {
+			return nil, fmt.Errorf("tag %q cannot be sent in a header, as it contains a comma", tag)
+		}
+	}
+
+	if m.Markdown {
+		set("X-Markdown", "yes")
+	}
+	set("X-Title", m.Title)
+	set("X-Tags", strings.Join(m.Tags, ","))
+	if m.Priority > 0 {
+		set("X-Priority", strconv.Itoa(int(m.Priority)))
+	}
+
+	if len(m.Actions) > 0 {
+		actions, err := FormatSimpleActions(m.Actions)
+		if err != nil {
+			return nil, err
+		}
+		set("X-Actions", actions)
+	}
+
+	for _, v := range []struct {
+		name string
+		url  *url.URL
+	}{
+		{"X-Click", m.ClickURL},
+		{"X-Icon", m.IconURL},
+		{"X-Attach", m.AttachURL},
+	} {
+		if v.url != nil {
+			set(v.name, v.url.String())
+		}
+	}
+
+	delay, err := m.delayValue()
+	if err != nil {
+		return nil, err
+	}
+	set("X-Delay", delay)
+	set("X-Email", m.Email)
+	set("X-Call", m.Call)
+	set("X-Filename", m.AttachURLFilename)
+
+	return h, nil
+}
+
+// headerValue encodes s for use as a header value, using an RFC 2047 encoded-word if it
+// contains anything but printable ASCII.
+func headerValue(s string) string {
+	return mime.BEncoding.Encode("UTF-8", s)
+}
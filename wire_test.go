@@ -0,0 +1,116 @@
+package gotfy
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Publisher_SendsWireFormat(t *testing.T) {
+	r := require.New(t)
+
+	var (
+		body   string
+		method string
+		path   string
+	)
+	serverURL := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		method = req.Method
+		path = req.URL.Path
+		buf, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		body = string(buf)
+
+		_, _ = io.WriteString(w, `{"id":"bUhbhgmmbeW0","time":1685150791,"expires":1685193991,"event":"message","topic":"mytopic","message":"triggered"}`)
+	}))
+
+	sut := NewPublisher(PublisherOpts{Server: serverURL})
+
+	resp, err := sut.Send(context.Background(), Message{
+		Topic:    "mytopic",
+		Message:  "triggered",
+		Priority: PriorityHigh,
+		Actions: []ActionButton{&ViewAction{
+			Label: "Open",
+			Link:  &url.URL{Scheme: "https", Host: "example.com"},
+		}},
+		ClickURL: &url.URL{Scheme: "https", Host: "click.example.com"},
+		Delay:    5 * time.Minute,
+	})
+	r.NoError(err)
+
+	r.Equal(http.MethodPost, method)
+	r.Equal("/", path)
+	r.Equal(`{"topic":"mytopic","message":"triggered","priority":4,"actions":[{"action":"view","label":"Open","url":"https://example.com"}],"click":"https://click.example.com","delay":"5m0s"}`, body)
+	r.Equal("bUhbhgmmbeW0", resp.ID)
+	r.Equal("triggered", resp.Message)
+}
+
+func Test_Publisher_SendsHeaderWireFormat(t *testing.T) {
+	r := require.New(t)
+
+	var dec mime.WordDecoder
+	serverURL := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "/mytopic", req.URL.Path)
+		assert.Equal(t, "text/plain; charset=utf-8", req.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer tk_0123456789", req.Header.Get("Authorization"))
+
+		title, err := dec.DecodeHeader(req.Header.Get("X-Title"))
+		assert.NoError(t, err)
+		assert.Equal(t, "Café ☕", title)
+		assert.Equal(t, "coffee,kitchen", req.Header.Get("X-Tags"))
+		assert.Equal(t, "5", req.Header.Get("X-Priority"))
+		assert.Equal(t, "view, Open, https://example.com; http, Brew, https://api.example.com/brew, method=PUT, body='{\"cups\": 2}'", req.Header.Get("X-Actions"))
+		assert.Equal(t, "https://click.example.com", req.Header.Get("X-Click"))
+		assert.Equal(t, "https://example.com/icon.png", req.Header.Get("X-Icon"))
+		assert.Equal(t, "5m0s", req.Header.Get("X-Delay"))
+		assert.Equal(t, "me@example.com", req.Header.Get("X-Email"))
+		assert.Equal(t, "+12223334444", req.Header.Get("X-Call"))
+		assert.Equal(t, "yes", req.Header.Get("X-Markdown"))
+		assert.Empty(t, req.Header.Get("X-Message"))
+
+		body, _ := io.ReadAll(req.Body)
+		assert.Equal(t, "The coffee is ready.\nEnjoy!", string(body))
+		_, _ = io.WriteString(w, `{"id":"bUhbhgmmbeW0","time":1685150791,"event":"message","topic":"mytopic"}`)
+	}))
+
+	sut := NewPublisher(PublisherOpts{Server: serverURL, Auth: AccessToken("tk_0123456789"), Wire: WireHeaders})
+
+	resp, err := sut.Send(context.Background(), Message{
+		Topic:    "mytopic",
+		Message:  "The coffee is ready.\nEnjoy!",
+		Markdown: true,
+		Title:    "Café ☕",
+		Tags:     []string{"coffee", "kitchen"},
+		Priority: PriorityMax,
+		Actions: []ActionButton{
+			&ViewAction{Label: "Open", Link: mustParseURL(r, "https://example.com")},
+			&HttpAction[string]{Label: "Brew", URL: mustParseURL(r, "https://api.example.com/brew"), Method: "PUT", Body: `{"cups": 2}`},
+		},
+		ClickURL: mustParseURL(r, "https://click.example.com"),
+		IconURL:  mustParseURL(r, "https://example.com/icon.png"),
+		Delay:    5 * time.Minute,
+		Email:    "me@example.com",
+		Call:     "+12223334444",
+	})
+	r.NoError(err)
+	r.Equal("bUhbhgmmbeW0", resp.ID)
+}
+
+func Test_Publisher_HeaderWireFormatRejectsCommasInTags(t *testing.T) {
+	r := require.New(t)
+	c := FakeHttpClient{CheckDo: func(*http.Request) { t.Error("no request should be sent") }}
+	sut := NewPublisher(PublisherOpts{HttpClient: &c, Wire: WireHeaders})
+
+	// the server would split the tag in two
+	_, err := sut.Send(context.Background(), Message{Topic: "mytopic", Tags: []string{"warning", "a,b"}})
+	r.EqualError(err, `tag "a,b" cannot be sent in a header, as it contains a comma`)
+}